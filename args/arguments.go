@@ -108,6 +108,17 @@ const (
 	IdentifyByTypeUsername IdentifyByType = "username"
 )
 
+// OverflowType is the policy of a subscription feed whose consumer falls behind
+type OverflowType string
+
+// overflow policies
+const (
+	OverflowTypeBlock      OverflowType = "block"      // waits for the consumer, stalling the connection
+	OverflowTypeDropOldest OverflowType = "dropOldest" // drops the oldest buffered message
+	OverflowTypeDropNewest OverflowType = "dropNewest" // drops the incoming message
+	OverflowTypeConflate   OverflowType = "conflate"   // keeps only the latest message
+)
+
 // BuildParams makes a map with the Arguments functions,
 // and check for the presence of "requireds" keys in the map,
 // raising an error if some required keys are not present.
@@ -400,3 +411,31 @@ func ID(val string) Argument {
 		params["id"] = val
 	}
 }
+
+// Depth returns a "depth" Argument
+func Depth(val int) Argument {
+	return func(params map[string]interface{}) {
+		params["depth"] = val
+	}
+}
+
+// Grouping returns a "grouping" Argument
+func Grouping(val string) Argument {
+	return func(params map[string]interface{}) {
+		params["grouping"] = val
+	}
+}
+
+// Buffer returns a "buffer" Argument
+func Buffer(val int) Argument {
+	return func(params map[string]interface{}) {
+		params["buffer"] = val
+	}
+}
+
+// Overflow returns a "overflow" Argument
+func Overflow(val OverflowType) Argument {
+	return func(params map[string]interface{}) {
+		params["overflow"] = val
+	}
+}
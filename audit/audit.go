@@ -0,0 +1,94 @@
+// Package audit keeps a tamper-evident log of the calls that change the
+// state of an account: orders, cancels, transfers, conversions and
+// withdrawals.
+//
+// Every record holds the hash of the previous one, so editing, removing or
+// reordering records breaks the chain, which Verify detects.
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Record is an entry of the log.
+type Record struct {
+	Seq      uint64            `json:"seq"`
+	Time     time.Time         `json:"time"`
+	Method   string            `json:"method"`
+	Params   map[string]string `json:"params,omitempty"`
+	ResultID string            `json:"resultId,omitempty"`
+	Error    string            `json:"error,omitempty"`
+	Prev     string            `json:"prev"`
+	Hash     string            `json:"hash"`
+}
+
+// computeHash returns the hash of the record, its own Hash field excluded.
+func (record Record) computeHash() string {
+	record.Hash = ""
+	data, _ := json.Marshal(record)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Sink stores the records of a log. Writes come in order, one at a time.
+type Sink interface {
+	Write(record Record) error
+}
+
+// SinkFunc adapts a function to a Sink.
+type SinkFunc func(record Record) error
+
+// Write calls the function.
+func (fn SinkFunc) Write(record Record) error {
+	return fn(record)
+}
+
+// Log appends hash-chained records to a sink. It is safe for concurrent use.
+type Log struct {
+	mutex sync.Mutex
+	sink  Sink
+	seq   uint64
+	prev  string
+	now   func() time.Time
+}
+
+// NewLog returns a log starting a new chain on sink.
+func NewLog(sink Sink) *Log {
+	return &Log{sink: sink, now: time.Now}
+}
+
+// Resume returns a log continuing the chain of last, the last record already
+// in sink.
+func Resume(sink Sink, last Record) *Log {
+	return &Log{sink: sink, seq: last.Seq, prev: last.Hash, now: time.Now}
+}
+
+// Append records a call: its method, its params, redacted with Redact, the id
+// of its result and its error, if any. The chain only advances if the sink
+// accepts the record.
+func (log *Log) Append(method string, params map[string]interface{}, resultID string, callErr error) error {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	record := Record{
+		Seq:      log.seq + 1,
+		Time:     log.now().UTC(),
+		Method:   method,
+		Params:   Redact(params),
+		ResultID: resultID,
+		Prev:     log.prev,
+	}
+	if callErr != nil {
+		record.Error = callErr.Error()
+	}
+	record.Hash = record.computeHash()
+	if err := log.sink.Write(record); err != nil {
+		return fmt.Errorf("CryptomarketSDKError: audit record not written: %v", err)
+	}
+	log.seq, log.prev = record.Seq, record.Hash
+	return nil
+}
@@ -0,0 +1,85 @@
+package audit
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func writeLog(t *testing.T, n int) []Record {
+	var buf bytes.Buffer
+	log := NewLog(WriterSink(&buf))
+	for i := 0; i < n; i++ {
+		if err := log.Append("POST order", map[string]interface{}{"symbol": "EOSETH", "address": "abc"}, "id", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	records, err := ReadRecords(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+func TestVerify(t *testing.T) {
+	records := writeLog(t, 4)
+	if err := Verify(records); err != nil {
+		t.Fatal(err)
+	}
+	if records[0].Params["address"] != redacted || records[0].Params["symbol"] != "EOSETH" {
+		t.Fatalf("wrong params: %v", records[0].Params)
+	}
+
+	edited := append([]Record{}, records...)
+	edited[1].ResultID = "other"
+	gap := append(append([]Record{}, records[:1]...), records[2:]...)
+	reordered := []Record{records[0], records[2], records[1], records[3]}
+	relinked := append([]Record{}, records...)
+	relinked[2].Prev = records[0].Hash
+	relinked[2].Hash = relinked[2].computeHash()
+	cases := map[string][]Record{"edited": edited, "gap": gap, "reordered": reordered, "relinked": relinked}
+	for name, records := range cases {
+		var verifyErr *VerifyError
+		if err := Verify(records); !errors.As(err, &verifyErr) {
+			t.Errorf("%v: expected a verify error, got %v", name, err)
+		}
+	}
+}
+
+func TestOpenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	for i := 0; i < 2; i++ {
+		log, file, err := OpenFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = log.Append("POST order", nil, "", errors.New("rejected")); err != nil {
+			t.Fatal(err)
+		}
+		file.Close()
+	}
+	log, file, err := OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if log.seq != 2 {
+		t.Fatalf("expected to resume at 2, got %v", log.seq)
+	}
+}
+
+func TestResultID(t *testing.T) {
+	cases := map[string]string{
+		`{"id":840450210,"clientOrderId":"d8574207d9e3b16a4a5511753eeef175"}`: "d8574207d9e3b16a4a5511753eeef175",
+		`{"id":"6a2fb54d-7466-490c-b3a6-95d8c882f7f7"}`:                       "6a2fb54d-7466-490c-b3a6-95d8c882f7f7",
+		`{"result":["a","b"]}`: "a,b",
+		`{"jsonrpc":"2.0","result":{"id":1,"clientOrderId":"abc"},"id":7}`: "abc",
+		`{"error":{"code":20001}}`: "",
+	}
+	for response, expected := range cases {
+		if id := ResultID([]byte(response)); id != expected {
+			t.Errorf("%v: got %q, expected %q", response, id, expected)
+		}
+	}
+}
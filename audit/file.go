@@ -0,0 +1,67 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// WriterSink returns a sink writing the records to w as json lines.
+func WriterSink(w io.Writer) Sink {
+	encoder := json.NewEncoder(w)
+	return SinkFunc(func(record Record) error {
+		return encoder.Encode(record)
+	})
+}
+
+// ReadRecords reads the json lines written by a WriterSink.
+func ReadRecords(r io.Reader) ([]Record, error) {
+	records := make([]Record, 0)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("CryptomarketSDKError: invalid audit record at line %d: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("CryptomarketSDKError: can't read the audit log: %v", err)
+	}
+	return records, nil
+}
+
+// OpenFile opens the log at path for appending, creating it if needed. An
+// existing log is verified before its chain is resumed. The file is synced
+// after every record, and closing it is up to the caller.
+func OpenFile(path string) (*Log, *os.File, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, nil, fmt.Errorf("CryptomarketSDKError: can't open the audit log: %v", err)
+	}
+	records, err := ReadRecords(file)
+	if err == nil {
+		err = Verify(records)
+	}
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	writer := WriterSink(file)
+	sink := SinkFunc(func(record Record) error {
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+		return file.Sync()
+	})
+	if len(records) == 0 {
+		return NewLog(sink), file, nil
+	}
+	return Resume(sink, records[len(records)-1]), file, nil
+}
@@ -0,0 +1,80 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// SensitiveParams are the params whose values are not written to the log.
+var SensitiveParams = []string{"address", "paymentId", "publicComment"}
+
+const redacted = "[redacted]"
+
+// Redact returns the params as strings, with the values of SensitiveParams
+// replaced.
+func Redact(params map[string]interface{}) map[string]string {
+	if len(params) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(params))
+	for key, value := range params {
+		switch v := value.(type) {
+		case []string:
+			result[key] = strings.Join(v, ",")
+		default:
+			result[key] = fmt.Sprint(v)
+		}
+	}
+	for _, key := range SensitiveParams {
+		if _, ok := result[key]; ok {
+			result[key] = redacted
+		}
+	}
+	return result
+}
+
+// ResultID extracts the id of the result of an exchange response: the client
+// order id of orders, the id of transactions, or the ids of a list of them
+// joined by commas. Responses wrapped in a "result" field, as in the
+// websocket api, are unwrapped.
+func ResultID(response []byte) string {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(response, &object); err == nil {
+		if result, ok := object["result"]; ok {
+			return ResultID(result)
+		}
+		for _, key := range []string{"clientOrderId", "id"} {
+			if id, ok := object[key]; ok {
+				return rawString(id)
+			}
+		}
+		return ""
+	}
+	var list []json.RawMessage
+	if err := json.Unmarshal(response, &list); err == nil {
+		ids := make([]string, 0, len(list))
+		for _, item := range list {
+			if id := ResultID(item); id != "" {
+				ids = append(ids, id)
+			} else if id := rawString(item); id != "" {
+				ids = append(ids, id)
+			}
+		}
+		return strings.Join(ids, ",")
+	}
+	return ""
+}
+
+// rawString returns a json string unquoted, and numbers as they are.
+func rawString(raw json.RawMessage) string {
+	var str string
+	if err := json.Unmarshal(raw, &str); err == nil {
+		return str
+	}
+	var number json.Number
+	if err := json.Unmarshal(raw, &number); err == nil {
+		return number.String()
+	}
+	return ""
+}
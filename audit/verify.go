@@ -0,0 +1,35 @@
+package audit
+
+import "fmt"
+
+// VerifyError tells the first record of a log breaking the chain.
+type VerifyError struct {
+	Seq    uint64 // the expected sequence number
+	Reason string
+}
+
+func (err *VerifyError) Error() string {
+	return fmt.Sprintf("CryptomarketSDKError: audit log broken at record %d: %s", err.Seq, err.Reason)
+}
+
+// Verify checks the chain of a whole log, from its first record: the
+// sequence numbers have no gaps, every record links to the previous one and
+// no record was edited. Returns a *VerifyError otherwise.
+func Verify(records []Record) error {
+	prev := ""
+	for idx, record := range records {
+		seq := uint64(idx + 1)
+		switch {
+		case record.Seq < seq:
+			return &VerifyError{Seq: seq, Reason: fmt.Sprintf("duplicated or reordered record %d", record.Seq)}
+		case record.Seq > seq:
+			return &VerifyError{Seq: seq, Reason: fmt.Sprintf("missing records, found %d", record.Seq)}
+		case record.Prev != prev:
+			return &VerifyError{Seq: seq, Reason: "not linked to the previous record"}
+		case record.computeHash() != record.Hash:
+			return &VerifyError{Seq: seq, Reason: "edited record"}
+		}
+		prev = record.Hash
+	}
+	return nil
+}
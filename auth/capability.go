@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Capability is a set of kinds of operations, the ones a client is allowed to
+// do, or the ones an operation needs. Capabilities are combined with |.
+type Capability uint
+
+// capabilities
+const (
+	MarketData  Capability = 1 << iota // public market data
+	ReadAccount                        // balances, orders, trades and transactions of the account
+	Trade                              // create, replace and cancel orders
+	Transfer                           // move funds inside the exchange, convert currencies, create deposit addresses
+	Withdraw                           // withdraw funds out of the exchange
+)
+
+// sets of capabilities
+const (
+	ReadOnly        = MarketData | ReadAccount
+	AllCapabilities = MarketData | ReadAccount | Trade | Transfer | Withdraw
+)
+
+var capabilityNames = []string{"market-data", "read-account", "trade", "transfer", "withdraw"}
+
+// Has reports if the capability holds all the required ones.
+func (capability Capability) Has(required Capability) bool {
+	return capability&required == required
+}
+
+func (capability Capability) String() string {
+	names := make([]string, 0)
+	for idx, name := range capabilityNames {
+		if capability&(1<<uint(idx)) != 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, "|")
+}
+
+// CapabilityError is the error of an operation rejected by the client
+// itself, for being out of the capabilities of the client.
+type CapabilityError struct {
+	Operation string     // the rejected operation, an http method and endpoint or a websocket method
+	Required  Capability // capabilities needed by the operation
+	Allowed   Capability // capabilities of the client
+}
+
+func (err *CapabilityError) Error() string {
+	return fmt.Sprintf("CryptomarketSDKError: %v requires the %v capability, the client only has %v", err.Operation, err.Required, err.Allowed)
+}
+
+// Check returns a CapabilityError if the allowed capabilities don't hold the
+// required ones of an operation.
+func Check(operation string, required, allowed Capability) error {
+	if allowed.Has(required) {
+		return nil
+	}
+	return &CapabilityError{Operation: operation, Required: required, Allowed: allowed}
+}
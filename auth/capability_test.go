@@ -0,0 +1,23 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCapability(t *testing.T) {
+	if !ReadOnly.Has(MarketData) || ReadOnly.Has(Trade) || !AllCapabilities.Has(Withdraw|Transfer) {
+		t.Fatal("wrong sets")
+	}
+	if s := (MarketData | Withdraw).String(); s != "market-data|withdraw" {
+		t.Fatalf("wrong string: %v", s)
+	}
+	if Check("GET public/ticker", MarketData, ReadOnly) != nil {
+		t.Fatal("market data should be allowed")
+	}
+	err := Check("POST account/crypto/withdraw", Withdraw, ReadOnly)
+	var capabilityErr *CapabilityError
+	if !errors.As(err, &capabilityErr) || capabilityErr.Required != Withdraw || capabilityErr.Allowed != ReadOnly {
+		t.Fatalf("expected a capability error, got %v", err)
+	}
+}
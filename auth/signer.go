@@ -0,0 +1,124 @@
+// Package auth signs the requests of the rest and websocket clients.
+//
+// A Signer builds the Authorization header of the rest requests and the
+// params of the websocket login. HS256 signs with a secret held in memory,
+// Basic sends the key and secret as HTTP basic auth, and External delegates
+// the HMAC to a SignFunc, e.g. a call to an HSM or a vault sidecar, so the
+// secret never enters the process.
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Request is a rest request to sign.
+type Request struct {
+	Method    string    // http method
+	Path      string    // path of the url, e.g. /api/2/order
+	Query     string    // url encoded params, of the url for GET requests and of the body otherwise
+	Timestamp time.Time // time of the request
+}
+
+// Message returns the message signed by the HS256 scheme: the http method,
+// the timestamp in seconds, the path and the query.
+func (req Request) Message() string {
+	msg := req.Method + strconv.FormatInt(req.Timestamp.Unix(), 10) + req.Path
+	if len(req.Query) != 0 {
+		if req.Method == "GET" {
+			msg += "?"
+		}
+		msg += req.Query
+	}
+	return msg
+}
+
+// Signer signs the requests of the clients.
+type Signer interface {
+	// Authorization returns the Authorization header of a rest request.
+	Authorization(ctx context.Context, req Request) (string, error)
+	// LoginParams returns the params of the login of a websocket client,
+	// given a random nonce.
+	LoginParams(ctx context.Context, nonce string) (map[string]interface{}, error)
+}
+
+// SignFunc returns the hex encoded HMAC-SHA256 of a message with the api secret.
+type SignFunc func(ctx context.Context, message string) (string, error)
+
+// HMAC returns a SignFunc computing the HMAC-SHA256 with the given secret.
+func HMAC(apiSecret string) SignFunc {
+	secret := []byte(apiSecret)
+	return func(ctx context.Context, message string) (string, error) {
+		h := hmac.New(sha256.New, secret)
+		h.Write([]byte(message))
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+}
+
+type hs256Signer struct {
+	apiKey string
+	sign   SignFunc
+}
+
+// HS256 returns a signer using the HS256 scheme, signing with the api secret.
+func HS256(apiKey, apiSecret string) Signer {
+	return External(apiKey, HMAC(apiSecret))
+}
+
+// External returns a signer using the HS256 scheme with signatures made by
+// sign, so the api secret can stay in an external key custody.
+func External(apiKey string, sign SignFunc) Signer {
+	return &hs256Signer{apiKey: apiKey, sign: sign}
+}
+
+func (signer *hs256Signer) Authorization(ctx context.Context, req Request) (string, error) {
+	signature, err := signer.sign(ctx, req.Message())
+	if err != nil {
+		return "", fmt.Errorf("CryptomarketSDKError: can't sign the request: %v", err)
+	}
+	timestamp := strconv.FormatInt(req.Timestamp.Unix(), 10)
+	return "HS256 " + base64.StdEncoding.EncodeToString([]byte(signer.apiKey+":"+timestamp+":"+signature)), nil
+}
+
+func (signer *hs256Signer) LoginParams(ctx context.Context, nonce string) (map[string]interface{}, error) {
+	signature, err := signer.sign(ctx, nonce)
+	if err != nil {
+		return nil, fmt.Errorf("CryptomarketSDKError: can't sign the login: %v", err)
+	}
+	return map[string]interface{}{
+		"algo":      "HS256",
+		"pKey":      signer.apiKey,
+		"nonce":     nonce,
+		"signature": signature,
+	}, nil
+}
+
+type basicSigner struct {
+	apiKey    string
+	apiSecret string
+}
+
+// Basic returns a signer using the Basic scheme, sending the api key and
+// secret on every request. Cheaper than HS256, but the secret travels over
+// the (encrypted) connection.
+func Basic(apiKey, apiSecret string) Signer {
+	return &basicSigner{apiKey: apiKey, apiSecret: apiSecret}
+}
+
+func (signer *basicSigner) Authorization(ctx context.Context, req Request) (string, error) {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(signer.apiKey+":"+signer.apiSecret)), nil
+}
+
+func (signer *basicSigner) LoginParams(ctx context.Context, nonce string) (map[string]interface{}, error) {
+	return map[string]interface{}{
+		"algo": "BASIC",
+		"pKey": signer.apiKey,
+		"sKey": signer.apiSecret,
+	}, nil
+}
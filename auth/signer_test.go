@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHS256(t *testing.T) {
+	req := Request{Method: "GET", Path: "/api/2/history/order", Query: "symbol=EOSETH", Timestamp: time.Unix(1611172800, 0)}
+	if msg := req.Message(); msg != "GET1611172800/api/2/history/order?symbol=EOSETH" {
+		t.Fatalf("wrong message: %v", msg)
+	}
+	header, err := HS256("key", "secret").Authorization(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(header, "HS256 ") {
+		t.Fatalf("wrong scheme: %v", header)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(header, "HS256 "))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, _ := HMAC("secret")(context.Background(), req.Message())
+	if string(decoded) != "key:1611172800:"+signature {
+		t.Fatalf("wrong credential: %s", decoded)
+	}
+	// an external signer with the same secret signs the same
+	external := External("key", func(ctx context.Context, message string) (string, error) {
+		return HMAC("secret")(ctx, message)
+	})
+	if other, _ := external.Authorization(context.Background(), req); other != header {
+		t.Fatalf("external signature differs: %v", other)
+	}
+	params, err := HS256("key", "secret").LoginParams(context.Background(), "nonce")
+	if err != nil || params["algo"] != "HS256" || params["pKey"] != "key" || params["nonce"] != "nonce" {
+		t.Fatalf("wrong login params: %v %v", params, err)
+	}
+}
+
+func TestExternalFailure(t *testing.T) {
+	signer := External("key", func(ctx context.Context, message string) (string, error) {
+		return "", errors.New("sidecar down")
+	})
+	if _, err := signer.Authorization(context.Background(), Request{}); err == nil {
+		t.Fatal("expected a signing error")
+	}
+	if _, err := signer.LoginParams(context.Background(), "nonce"); err == nil {
+		t.Fatal("expected a signing error")
+	}
+}
+
+func TestBasic(t *testing.T) {
+	header, _ := Basic("key", "secret").Authorization(context.Background(), Request{})
+	if header != "Basic "+base64.StdEncoding.EncodeToString([]byte("key:secret")) {
+		t.Fatalf("wrong header: %v", header)
+	}
+	params, _ := Basic("key", "secret").LoginParams(context.Background(), "nonce")
+	if params["algo"] != "BASIC" || params["sKey"] != "secret" {
+		t.Fatalf("wrong login params: %v", params)
+	}
+}
@@ -0,0 +1,385 @@
+// Package candles builds and transforms OHLCV candles on the client side.
+package candles
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/cryptomarket/cryptomarket-go/internal/decimal"
+	"github.com/cryptomarket/cryptomarket-go/models"
+)
+
+// TimestampFormat is the format of the timestamps of the candles built by this package,
+// the same used by the exchange.
+const TimestampFormat = "2006-01-02T15:04:05.000Z"
+
+// places are the decimal places of the values of the candles. Values built
+// from trades are sums and products of decimals, so 18 places are exact.
+const places = 18
+
+type barKind int
+
+const (
+	barKindTime barKind = iota
+	barKindVolume
+	barKindDollar
+)
+
+// recentIDs is the number of IDs of the trades of closed candles kept to tell
+// duplicates from late trades.
+const recentIDs = 10000
+
+// BuilderStats are the counters of the trades seen by a Builder.
+type BuilderStats struct {
+	Trades     int64 // trades added to a candle
+	Duplicates int64 // trades dropped for having the ID of a trade added recently
+	Late       int64 // trades dropped for belonging to an already closed candle, or older than it
+	Invalid    int64 // trades dropped for having an invalid price, quantity or timestamp
+}
+
+type bar struct {
+	start       time.Time
+	openTime    time.Time
+	openID      int64
+	closeTime   time.Time
+	closeID     int64
+	open        *big.Rat
+	close       *big.Rat
+	min         *big.Rat
+	max         *big.Rat
+	volume      *big.Rat
+	volumeQuote *big.Rat
+	ids         []int64
+}
+
+type trade struct {
+	id       int64
+	time     time.Time
+	price    *big.Rat
+	quantity *big.Rat
+}
+
+// Builder builds candles from public trades, like the ones of the trades
+// subscription or of the trades history. Trades are deduplicated by ID.
+//
+// Time candles are aligned to multiples of their duration since the unix epoch,
+// and are closed once a trade at least allowedLateness past their end is seen.
+// Trades for closed candles, or more than allowedLateness behind the newest
+// trade seen, are dropped as late.
+//
+// Volume and dollar candles close as soon as their volume (or quote volume)
+// reaches the threshold, without splitting trades between candles.
+type Builder struct {
+	kind      barKind
+	duration  time.Duration
+	lateness  time.Duration
+	threshold *big.Rat
+
+	bars      map[int64]*bar // open bars, by start
+	seen      map[int64]bool // ids of the trades in open bars
+	recent    map[int64]bool // ids of the last trades of closed bars
+	recentIDs []int64        // recent, oldest first
+	lastID    int64          // highest id of closed volume and dollar bars
+	closedEnd time.Time      // end of the last closed time bar
+	watermark time.Time
+	stats     BuilderStats
+}
+
+// NewTimeBuilder returns a builder of candles of the given duration, e.g. 10
+// seconds or 2 minutes. Trades up to allowedLateness behind the newest trade
+// seen are still added to their candle, older ones are dropped as late.
+func NewTimeBuilder(duration, allowedLateness time.Duration) (*Builder, error) {
+	if duration <= 0 || allowedLateness < 0 {
+		return nil, fmt.Errorf("CryptomarketSDKError: invalid candle duration: %v", duration)
+	}
+	return newBuilder(barKindTime, duration, allowedLateness, nil), nil
+}
+
+// NewVolumeBuilder returns a builder of candles that close when their base
+// currency volume reaches volume.
+func NewVolumeBuilder(volume string) (*Builder, error) {
+	threshold, err := parseThreshold(volume)
+	if err != nil {
+		return nil, err
+	}
+	return newBuilder(barKindVolume, 0, 0, threshold), nil
+}
+
+// NewDollarBuilder returns a builder of candles that close when their quote
+// currency volume reaches amount.
+func NewDollarBuilder(amount string) (*Builder, error) {
+	threshold, err := parseThreshold(amount)
+	if err != nil {
+		return nil, err
+	}
+	return newBuilder(barKindDollar, 0, 0, threshold), nil
+}
+
+func newBuilder(kind barKind, duration, lateness time.Duration, threshold *big.Rat) *Builder {
+	return &Builder{
+		kind:      kind,
+		duration:  duration,
+		lateness:  lateness,
+		threshold: threshold,
+		bars:      make(map[int64]*bar),
+		seen:      make(map[int64]bool),
+		recent:    make(map[int64]bool),
+	}
+}
+
+func parseThreshold(value string) (*big.Rat, error) {
+	threshold, ok := new(big.Rat).SetString(value)
+	if !ok || threshold.Sign() <= 0 {
+		return nil, fmt.Errorf("CryptomarketSDKError: invalid candle threshold: %q", value)
+	}
+	return threshold, nil
+}
+
+// Stats returns the counters of the trades seen by the builder.
+func (builder *Builder) Stats() BuilderStats {
+	return builder.stats
+}
+
+// Add adds trades to the builder, in any order, and returns the candles
+// closed by them, oldest first.
+func (builder *Builder) Add(trades ...models.PublicTrade) []models.Candle {
+	parsed := make([]trade, 0, len(trades))
+	for _, publicTrade := range trades {
+		t, err := parseTrade(publicTrade)
+		if err != nil {
+			builder.stats.Invalid++
+			continue
+		}
+		parsed = append(parsed, t)
+	}
+	sort.SliceStable(parsed, func(i, j int) bool {
+		if parsed[i].time.Equal(parsed[j].time) {
+			return parsed[i].id < parsed[j].id
+		}
+		return parsed[i].time.Before(parsed[j].time)
+	})
+	closed := make([]models.Candle, 0)
+	for _, t := range parsed {
+		if builder.kind == barKindTime {
+			closed = append(closed, builder.addToTimeBar(t)...)
+		} else {
+			closed = append(closed, builder.addToThresholdBar(t)...)
+		}
+	}
+	return closed
+}
+
+// Flush closes and returns all the open candles, oldest first. Used at the
+// end of historical data, or when a feed ends.
+func (builder *Builder) Flush() []models.Candle {
+	return builder.closeBars(func(*bar) bool { return true })
+}
+
+// Run builds candles from a trades feed, such as the one of a trades
+// subscription, until the feed is closed. The open candles are flushed
+// before closing the returned channel.
+func (builder *Builder) Run(feedCh <-chan []models.PublicTrade) <-chan models.Candle {
+	candleCh := make(chan models.Candle)
+	go func() {
+		defer close(candleCh)
+		for trades := range feedCh {
+			for _, candle := range builder.Add(trades...) {
+				candleCh <- candle
+			}
+		}
+		for _, candle := range builder.Flush() {
+			candleCh <- candle
+		}
+	}()
+	return candleCh
+}
+
+func parseTrade(publicTrade models.PublicTrade) (trade, error) {
+	t, err := time.Parse(time.RFC3339, publicTrade.Timestamp)
+	if err != nil {
+		return trade{}, err
+	}
+	price, ok := new(big.Rat).SetString(publicTrade.Price)
+	if !ok {
+		return trade{}, fmt.Errorf("invalid price %q", publicTrade.Price)
+	}
+	quantity, ok := new(big.Rat).SetString(publicTrade.Quantity)
+	if !ok {
+		return trade{}, fmt.Errorf("invalid quantity %q", publicTrade.Quantity)
+	}
+	return trade{id: publicTrade.ID, time: t, price: price, quantity: quantity}, nil
+}
+
+func (builder *Builder) addToTimeBar(t trade) []models.Candle {
+	start := alignTime(t.time, builder.duration)
+	if builder.duplicate(t.id) {
+		builder.stats.Duplicates++
+		return nil
+	}
+	// behind the allowed lateness, or in a closed bar
+	if t.time.Add(builder.lateness).Before(builder.watermark) || start.Before(builder.closedEnd) {
+		builder.stats.Late++
+		return nil
+	}
+	builder.addTrade(start, t)
+	if t.time.After(builder.watermark) {
+		builder.watermark = t.time
+	}
+	// bars whose end plus the allowed lateness is already behind the watermark can't change
+	return builder.closeBars(func(b *bar) bool {
+		return !b.start.Add(builder.duration + builder.lateness).After(builder.watermark)
+	})
+}
+
+func (builder *Builder) addToThresholdBar(t trade) []models.Candle {
+	if builder.duplicate(t.id) {
+		builder.stats.Duplicates++
+		return nil
+	}
+	// out of order, after its bar was closed
+	if t.id != 0 && t.id <= builder.lastID {
+		builder.stats.Late++
+		return nil
+	}
+	var current *bar
+	for _, b := range builder.bars {
+		current = b
+	}
+	start := t.time
+	if current != nil {
+		start = current.start
+	}
+	builder.addTrade(start, t)
+	current = builder.bars[start.UnixNano()]
+	size := current.volume
+	if builder.kind == barKindDollar {
+		size = current.volumeQuote
+	}
+	if size.Cmp(builder.threshold) < 0 {
+		return nil
+	}
+	for _, id := range current.ids {
+		if id > builder.lastID {
+			builder.lastID = id
+		}
+	}
+	return builder.Flush()
+}
+
+// duplicate reports if a trade with the id was added to an open bar, or to one
+// of the last closed ones.
+func (builder *Builder) duplicate(id int64) bool {
+	return builder.seen[id] || builder.recent[id]
+}
+
+// remember keeps the id of a trade of a closed bar, forgetting the oldest one
+// beyond recentIDs. Trades without an id are not deduplicated once closed.
+func (builder *Builder) remember(id int64) {
+	if id == 0 || builder.recent[id] {
+		return
+	}
+	if len(builder.recentIDs) == recentIDs {
+		delete(builder.recent, builder.recentIDs[0])
+		builder.recentIDs = builder.recentIDs[1:]
+	}
+	builder.recent[id] = true
+	builder.recentIDs = append(builder.recentIDs, id)
+}
+
+func (builder *Builder) addTrade(start time.Time, t trade) {
+	builder.stats.Trades++
+	builder.seen[t.id] = true
+	amount := new(big.Rat).Mul(t.price, t.quantity)
+	b, ok := builder.bars[start.UnixNano()]
+	if !ok {
+		builder.bars[start.UnixNano()] = &bar{
+			start:       start,
+			openTime:    t.time,
+			openID:      t.id,
+			closeTime:   t.time,
+			closeID:     t.id,
+			open:        t.price,
+			close:       t.price,
+			min:         t.price,
+			max:         t.price,
+			volume:      new(big.Rat).Set(t.quantity),
+			volumeQuote: amount,
+			ids:         []int64{t.id},
+		}
+		return
+	}
+	if before(t.time, t.id, b.openTime, b.openID) {
+		b.open, b.openTime, b.openID = t.price, t.time, t.id
+	}
+	if !before(t.time, t.id, b.closeTime, b.closeID) {
+		b.close, b.closeTime, b.closeID = t.price, t.time, t.id
+	}
+	if t.price.Cmp(b.min) < 0 {
+		b.min = t.price
+	}
+	if t.price.Cmp(b.max) > 0 {
+		b.max = t.price
+	}
+	b.volume.Add(b.volume, t.quantity)
+	b.volumeQuote.Add(b.volumeQuote, amount)
+	b.ids = append(b.ids, t.id)
+}
+
+// closeBars closes the open bars for which shouldClose holds, returning their candles oldest first.
+func (builder *Builder) closeBars(shouldClose func(*bar) bool) []models.Candle {
+	closing := make([]*bar, 0)
+	for key, b := range builder.bars {
+		if shouldClose(b) {
+			closing = append(closing, b)
+			delete(builder.bars, key)
+		}
+	}
+	sort.Slice(closing, func(i, j int) bool { return closing[i].start.Before(closing[j].start) })
+	candles := make([]models.Candle, 0, len(closing))
+	for _, b := range closing {
+		for _, id := range b.ids {
+			delete(builder.seen, id)
+			builder.remember(id)
+		}
+		if builder.kind == barKindTime {
+			if end := b.start.Add(builder.duration); end.After(builder.closedEnd) {
+				builder.closedEnd = end
+			}
+		}
+		candles = append(candles, b.candle())
+	}
+	return candles
+}
+
+func (b *bar) candle() models.Candle {
+	return models.Candle{
+		Timestamp:   b.start.UTC().Format(TimestampFormat),
+		Open:        decimal.Format(b.open, places),
+		Close:       decimal.Format(b.close, places),
+		Min:         decimal.Format(b.min, places),
+		Max:         decimal.Format(b.max, places),
+		Volume:      decimal.Format(b.volume, places),
+		VolumeQuote: decimal.Format(b.volumeQuote, places),
+	}
+}
+
+func before(t time.Time, id int64, other time.Time, otherID int64) bool {
+	if t.Equal(other) {
+		return id < otherID
+	}
+	return t.Before(other)
+}
+
+// alignTime returns the start of the window of the given duration holding t,
+// windows being aligned to the unix epoch.
+func alignTime(t time.Time, duration time.Duration) time.Time {
+	nanos := t.UnixNano()
+	offset := nanos % int64(duration)
+	if offset < 0 {
+		offset += int64(duration)
+	}
+	return time.Unix(0, nanos-offset).UTC()
+}
+
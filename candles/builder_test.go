@@ -0,0 +1,145 @@
+package candles
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cryptomarket/cryptomarket-go/models"
+)
+
+var baseTime = time.Date(2021, 1, 20, 20, 0, 0, 0, time.UTC)
+
+func tradeAt(id int64, seconds int, price, quantity string) models.PublicTrade {
+	return models.PublicTrade{
+		ID:        id,
+		Price:     price,
+		Quantity:  quantity,
+		Side:      models.SideTypeBuy,
+		Timestamp: baseTime.Add(time.Duration(seconds) * time.Second).Format(TimestampFormat),
+	}
+}
+
+func TestTimeBuilder(t *testing.T) {
+	builder, err := NewTimeBuilder(10*time.Second, 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := builder.Add(
+		tradeAt(2, 5, "11", "1"),
+		tradeAt(1, 1, "10", "2"),
+		tradeAt(3, 9, "9", "1"),
+	)
+	if len(closed) != 0 {
+		t.Fatalf("no candle should be closed yet: %v", closed)
+	}
+	// within the allowed lateness the first candle is still open
+	closed = builder.Add(tradeAt(4, 11, "12", "1"), tradeAt(5, 9, "13", "1"), tradeAt(2, 5, "11", "1"))
+	if len(closed) != 0 {
+		t.Fatalf("no candle should be closed yet: %v", closed)
+	}
+	closed = builder.Add(tradeAt(6, 12, "12", "1"))
+	if len(closed) != 1 {
+		t.Fatalf("expected a closed candle, got %v", closed)
+	}
+	expected := models.Candle{
+		Timestamp:   "2021-01-20T20:00:00.000Z",
+		Open:        "10",
+		Close:       "13",
+		Min:         "9",
+		Max:         "13",
+		Volume:      "5",
+		VolumeQuote: "53",
+	}
+	if closed[0] != expected {
+		t.Fatalf("wrong candle:\n%+v\nexpected\n%+v", closed[0], expected)
+	}
+	if closed = builder.Add(tradeAt(7, 3, "1", "1")); len(closed) != 0 {
+		t.Fatal("late trades should not build candles")
+	}
+	stats := builder.Stats()
+	if stats.Late != 1 || stats.Duplicates != 1 || stats.Trades != 6 {
+		t.Fatalf("wrong stats: %+v", stats)
+	}
+	closed = builder.Flush()
+	if len(closed) != 1 || closed[0].Timestamp != "2021-01-20T20:00:10.000Z" || closed[0].Volume != "2" {
+		t.Fatalf("wrong flushed candles: %v", closed)
+	}
+}
+
+func TestTimeBuilderLateness(t *testing.T) {
+	builder, err := NewTimeBuilder(time.Minute, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := builder.Add(tradeAt(1, 10, "10", "1"), tradeAt(2, 310, "11", "1"))
+	if len(closed) != 1 || closed[0].Timestamp != "2021-01-20T20:00:00.000Z" {
+		t.Fatalf("wrong candles: %v", closed)
+	}
+	// its window is not closed, but it is behind the watermark
+	if closed = builder.Add(tradeAt(3, 130, "12", "1")); len(closed) != 0 {
+		t.Fatalf("a late trade built candles: %v", closed)
+	}
+	if stats := builder.Stats(); stats.Late != 1 || stats.Trades != 2 {
+		t.Fatalf("wrong stats: %+v", stats)
+	}
+	if closed = builder.Flush(); len(closed) != 1 || closed[0].Timestamp != "2021-01-20T20:05:00.000Z" {
+		t.Fatalf("wrong flushed candles: %v", closed)
+	}
+}
+
+func TestVolumeBuilder(t *testing.T) {
+	builder, err := NewVolumeBuilder("3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := builder.Add(tradeAt(1, 0, "10", "1"), tradeAt(2, 1, "11", "1.5"), tradeAt(3, 2, "12", "1"), tradeAt(4, 3, "13", "1"))
+	if len(closed) != 1 || closed[0].Volume != "3.5" || closed[0].Close != "12" {
+		t.Fatalf("wrong volume candles: %v", closed)
+	}
+	// trades of a closed candle are duplicates
+	if closed = builder.Add(tradeAt(2, 1, "11", "1.5")); len(closed) != 0 || builder.Stats().Duplicates != 1 {
+		t.Fatal("duplicate trade not dropped")
+	}
+	// a trade older than a closed candle, but not seen, is late
+	if closed = builder.Add(tradeAt(6, 5, "10", "3")); len(closed) != 1 {
+		t.Fatalf("wrong volume candles: %v", closed)
+	}
+	if closed = builder.Add(tradeAt(5, 4, "10", "1"), tradeAt(6, 5, "10", "3")); len(closed) != 0 {
+		t.Fatal("out of order trades should not build candles")
+	}
+	if stats := builder.Stats(); stats.Late != 1 || stats.Duplicates != 2 {
+		t.Fatalf("wrong stats: %+v", stats)
+	}
+	if _, err = NewVolumeBuilder("0"); err == nil {
+		t.Fatal("should fail with a zero threshold")
+	}
+}
+
+func TestDollarBuilder(t *testing.T) {
+	builder, err := NewDollarBuilder("20")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := builder.Add(tradeAt(1, 0, "10", "1"), tradeAt(2, 1, "10", "1"), tradeAt(3, 2, "10", "1"))
+	if len(closed) != 1 || closed[0].VolumeQuote != "20" {
+		t.Fatalf("wrong dollar candles: %v", closed)
+	}
+}
+
+func TestBuilderRun(t *testing.T) {
+	builder, _ := NewTimeBuilder(time.Minute, 0)
+	feedCh := make(chan []models.PublicTrade)
+	candleCh := builder.Run(feedCh)
+	go func() {
+		feedCh <- []models.PublicTrade{tradeAt(1, 0, "1", "1")}
+		feedCh <- []models.PublicTrade{tradeAt(2, 60, "2", "1")}
+		close(feedCh)
+	}()
+	count := 0
+	for range candleCh {
+		count++
+	}
+	if count != 2 {
+		t.Fatalf("expected 2 candles, got %v", count)
+	}
+}
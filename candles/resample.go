@@ -0,0 +1,234 @@
+package candles
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/cryptomarket/cryptomarket-go/args"
+	"github.com/cryptomarket/cryptomarket-go/internal/decimal"
+	"github.com/cryptomarket/cryptomarket-go/models"
+)
+
+var periodDurations = map[args.PeriodType]time.Duration{
+	args.PeriodType1Minutes:  time.Minute,
+	args.PeriodType3Minutes:  3 * time.Minute,
+	args.PeriodType5Minutes:  5 * time.Minute,
+	args.PeriodType15Minutes: 15 * time.Minute,
+	args.PeriodType30Minutes: 30 * time.Minute,
+	args.PeriodType1Hours:    time.Hour,
+	args.PeriodType4Hours:    4 * time.Hour,
+	args.PeriodType1Day:      24 * time.Hour,
+	args.PeriodType7Days:     7 * 24 * time.Hour,
+}
+
+// PeriodDuration returns the duration of a candle period. Fails for
+// PeriodType1Month, as months have no fixed duration.
+func PeriodDuration(period args.PeriodType) (time.Duration, error) {
+	if duration, ok := periodDurations[period]; ok {
+		return duration, nil
+	}
+	return 0, fmt.Errorf("CryptomarketSDKError: period without a fixed duration: %q", period)
+}
+
+type parsedCandle struct {
+	time        time.Time
+	open        *big.Rat
+	close       *big.Rat
+	min         *big.Rat
+	max         *big.Rat
+	volume      *big.Rat
+	volumeQuote *big.Rat
+}
+
+func parseCandle(candle models.Candle) (parsedCandle, error) {
+	t, err := time.Parse(time.RFC3339, candle.Timestamp)
+	if err != nil {
+		return parsedCandle{}, fmt.Errorf("CryptomarketSDKError: invalid candle timestamp: %q", candle.Timestamp)
+	}
+	parsed := parsedCandle{time: t}
+	fields := []struct {
+		dst   **big.Rat
+		value string
+	}{
+		{&parsed.open, candle.Open},
+		{&parsed.close, candle.Close},
+		{&parsed.min, candle.Min},
+		{&parsed.max, candle.Max},
+		{&parsed.volume, candle.Volume},
+		{&parsed.volumeQuote, candle.VolumeQuote},
+	}
+	for _, field := range fields {
+		value, ok := new(big.Rat).SetString(field.value)
+		if !ok {
+			return parsedCandle{}, fmt.Errorf("CryptomarketSDKError: invalid candle value %q at %v", field.value, candle.Timestamp)
+		}
+		*field.dst = value
+	}
+	return parsed, nil
+}
+
+func parseSorted(candles []models.Candle) ([]parsedCandle, error) {
+	parsed := make([]parsedCandle, 0, len(candles))
+	for _, candle := range candles {
+		p, err := parseCandle(candle)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, p)
+	}
+	sort.SliceStable(parsed, func(i, j int) bool { return parsed[i].time.Before(parsed[j].time) })
+	return parsed, nil
+}
+
+func (p parsedCandle) candle() models.Candle {
+	return models.Candle{
+		Timestamp:   p.time.UTC().Format(TimestampFormat),
+		Open:        decimal.Format(p.open, places),
+		Close:       decimal.Format(p.close, places),
+		Min:         decimal.Format(p.min, places),
+		Max:         decimal.Format(p.max, places),
+		Volume:      decimal.Format(p.volume, places),
+		VolumeQuote: decimal.Format(p.volumeQuote, places),
+	}
+}
+
+// Resample groups candles, in any order, into candles of a larger period.
+// Candles are aligned to multiples of the period since the unix epoch, and
+// months to calendar months in UTC. The result is sorted oldest first.
+func Resample(candles []models.Candle, period args.PeriodType) ([]models.Candle, error) {
+	if period == args.PeriodType1Month {
+		return resample(candles, func(t time.Time) time.Time {
+			t = t.UTC()
+			return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		})
+	}
+	duration, err := PeriodDuration(period)
+	if err != nil {
+		return nil, err
+	}
+	return ResampleDuration(candles, duration)
+}
+
+// ResampleDuration groups candles, in any order, into candles of the given
+// duration, aligned to multiples of it since the unix epoch. The duration
+// should be a multiple of the period of the candles. The result is sorted oldest first.
+func ResampleDuration(candles []models.Candle, duration time.Duration) ([]models.Candle, error) {
+	if duration <= 0 {
+		return nil, fmt.Errorf("CryptomarketSDKError: invalid candle duration: %v", duration)
+	}
+	return resample(candles, func(t time.Time) time.Time { return alignTime(t, duration) })
+}
+
+func resample(candles []models.Candle, bucketStart func(time.Time) time.Time) ([]models.Candle, error) {
+	parsed, err := parseSorted(candles)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]models.Candle, 0)
+	var current *parsedCandle
+	for _, p := range parsed {
+		start := bucketStart(p.time)
+		if current != nil && current.time.Equal(start) {
+			current.close = p.close
+			if p.min.Cmp(current.min) < 0 {
+				current.min = p.min
+			}
+			if p.max.Cmp(current.max) > 0 {
+				current.max = p.max
+			}
+			current.volume.Add(current.volume, p.volume)
+			current.volumeQuote.Add(current.volumeQuote, p.volumeQuote)
+			continue
+		}
+		if current != nil {
+			result = append(result, current.candle())
+		}
+		bucket := p
+		bucket.time = start
+		current = &bucket
+	}
+	if current != nil {
+		result = append(result, current.candle())
+	}
+	return result, nil
+}
+
+// FillGaps returns the candles, in any order, sorted oldest first and with the
+// missing periods of the given duration filled with flat candles: open, close,
+// min and max equal to the previous close, and zero volume.
+func FillGaps(candles []models.Candle, duration time.Duration) ([]models.Candle, error) {
+	if duration <= 0 {
+		return nil, fmt.Errorf("CryptomarketSDKError: invalid candle duration: %v", duration)
+	}
+	parsed, err := parseSorted(candles)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]models.Candle, 0, len(parsed))
+	for idx, p := range parsed {
+		if idx > 0 {
+			last := parsed[idx-1]
+			for t := last.time.Add(duration); t.Before(p.time); t = t.Add(duration) {
+				result = append(result, models.Candle{
+					Timestamp:   t.UTC().Format(TimestampFormat),
+					Open:        decimal.Format(last.close, places),
+					Close:       decimal.Format(last.close, places),
+					Min:         decimal.Format(last.close, places),
+					Max:         decimal.Format(last.close, places),
+					Volume:      "0",
+					VolumeQuote: "0",
+				})
+			}
+		}
+		result = append(result, p.candle())
+	}
+	return result, nil
+}
+
+// Validate checks that the candles have valid values and strictly increasing
+// timestamps, that is, sorted oldest first and without duplicates.
+func Validate(candles []models.Candle) error {
+	var last time.Time
+	for idx, candle := range candles {
+		p, err := parseCandle(candle)
+		if err != nil {
+			return err
+		}
+		if idx > 0 && !p.time.After(last) {
+			return fmt.Errorf("CryptomarketSDKError: non increasing candle timestamp at index %v: %v", idx, candle.Timestamp)
+		}
+		if p.min.Cmp(p.max) > 0 {
+			return fmt.Errorf("CryptomarketSDKError: candle min above max at %v", candle.Timestamp)
+		}
+		last = p.time
+	}
+	return nil
+}
+
+// Merge merges batches of candles, possibly overlapping, into a single series
+// sorted oldest first. When batches share a timestamp, the candle of the
+// latest batch is kept, as it is the freshest download.
+func Merge(batches ...[]models.Candle) ([]models.Candle, error) {
+	byTime := make(map[int64]models.Candle)
+	for _, batch := range batches {
+		for _, candle := range batch {
+			t, err := time.Parse(time.RFC3339, candle.Timestamp)
+			if err != nil {
+				return nil, fmt.Errorf("CryptomarketSDKError: invalid candle timestamp: %q", candle.Timestamp)
+			}
+			byTime[t.UnixNano()] = candle
+		}
+	}
+	keys := make([]int64, 0, len(byTime))
+	for key := range byTime {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	result := make([]models.Candle, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, byTime[key])
+	}
+	return result, nil
+}
@@ -0,0 +1,88 @@
+package candles
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cryptomarket/cryptomarket-go/args"
+	"github.com/cryptomarket/cryptomarket-go/models"
+)
+
+func minuteCandle(minute int, open, close, min, max string) models.Candle {
+	return models.Candle{
+		Timestamp:   baseTime.Add(time.Duration(minute) * time.Minute).Format(TimestampFormat),
+		Open:        open,
+		Close:       close,
+		Min:         min,
+		Max:         max,
+		Volume:      "1",
+		VolumeQuote: "10",
+	}
+}
+
+func TestResample(t *testing.T) {
+	candles := []models.Candle{
+		minuteCandle(3, "12", "13", "11", "14"),
+		minuteCandle(0, "10", "11", "9", "11"),
+		minuteCandle(1, "11", "12", "10", "15"),
+		minuteCandle(5, "13", "10", "8", "13"),
+	}
+	result, err := Resample(candles, args.PeriodType5Minutes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 2 {
+		t.Fatalf("expected 2 candles, got %v", result)
+	}
+	expected := models.Candle{Timestamp: "2021-01-20T20:00:00.000Z", Open: "10", Close: "13", Min: "9", Max: "15", Volume: "3", VolumeQuote: "30"}
+	if result[0] != expected {
+		t.Fatalf("wrong candle:\n%+v\nexpected\n%+v", result[0], expected)
+	}
+	monthly, err := Resample(candles, args.PeriodType1Month)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(monthly) != 1 || monthly[0].Timestamp != "2021-01-01T00:00:00.000Z" {
+		t.Fatalf("wrong monthly candles: %v", monthly)
+	}
+}
+
+func TestFillGaps(t *testing.T) {
+	candles := []models.Candle{minuteCandle(0, "10", "11", "9", "11"), minuteCandle(3, "12", "13", "11", "14")}
+	result, err := FillGaps(candles, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 4 {
+		t.Fatalf("expected 4 candles, got %v", result)
+	}
+	gap := result[1]
+	if gap.Open != "11" || gap.Close != "11" || gap.Max != "11" || gap.Volume != "0" || gap.Timestamp != "2021-01-20T20:01:00.000Z" {
+		t.Fatalf("wrong gap candle: %+v", gap)
+	}
+	if err = Validate(result); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	unsorted := []models.Candle{minuteCandle(1, "1", "1", "1", "1"), minuteCandle(0, "1", "1", "1", "1")}
+	if err := Validate(unsorted); err == nil {
+		t.Fatal("should fail on unsorted candles")
+	}
+	if err := Validate([]models.Candle{minuteCandle(0, "1", "1", "2", "1")}); err == nil {
+		t.Fatal("should fail with min above max")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	first := []models.Candle{minuteCandle(0, "1", "1", "1", "1"), minuteCandle(1, "1", "1", "1", "1")}
+	second := []models.Candle{minuteCandle(2, "2", "2", "2", "2"), minuteCandle(1, "2", "2", "2", "2")}
+	result, err := Merge(first, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 3 || result[1].Open != "2" {
+		t.Fatalf("wrong merge: %v", result)
+	}
+}
@@ -0,0 +1,103 @@
+// Command cryptomkt-download downloads historical candles or trades of many
+// symbols into CSV or JSONL files, one per symbol. Downloads are
+// checkpointed, so running the same command again resumes an interrupted one.
+//
+// Usage:
+//
+//	cryptomkt-download -kind candles -symbols EOSETH,ETHBTC -period M1 -from 2021-01-01T00:00:00Z -till 2022-01-01T00:00:00Z -dir data
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/cryptomarket/cryptomarket-go/args"
+	"github.com/cryptomarket/cryptomarket-go/downloader"
+	"github.com/cryptomarket/cryptomarket-go/rest"
+)
+
+func main() {
+	kind := flag.String("kind", "candles", "data to download, candles or trades")
+	symbols := flag.String("symbols", "", "comma separated symbols")
+	period := flag.String("period", string(args.PeriodType1Minutes), "period of the candles")
+	from := flag.String("from", "", "start of the download, RFC3339, included")
+	till := flag.String("till", "", "end of the download, RFC3339, excluded, default now")
+	dir := flag.String("dir", ".", "directory of the files and checkpoints")
+	format := flag.String("format", string(downloader.FormatCSV), "format of the files, csv or jsonl")
+	workers := flag.Int("workers", 4, "symbols downloaded in parallel")
+	rate := flag.Int("rate", 10, "maximum requests per second")
+	flag.Parse()
+
+	if err := run(rest.NewClient("", ""), *kind, *symbols, *period, *from, *till, *dir, *format, *workers, *rate); err != nil {
+		fmt.Fprintln(os.Stderr, "cryptomkt-download:", err)
+		os.Exit(1)
+	}
+}
+
+func run(source downloader.Source, kind, symbols, period, from, till, dir, format string, workers, rate int) error {
+	if symbols == "" {
+		return fmt.Errorf("no symbols")
+	}
+	fromTime, err := time.Parse(time.RFC3339, from)
+	if err != nil {
+		return fmt.Errorf("invalid from: %v", err)
+	}
+	tillTime := time.Now().UTC()
+	if till != "" {
+		if tillTime, err = time.Parse(time.RFC3339, till); err != nil {
+			return fmt.Errorf("invalid till: %v", err)
+		}
+	}
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		cancel()
+	}()
+
+	d := downloader.New(
+		source,
+		dir,
+		downloader.WithFormat(downloader.Format(format)),
+		downloader.WithWorkers(workers),
+		downloader.WithRateLimit(rate),
+	)
+	var results []downloader.Result
+	switch kind {
+	case "candles":
+		results, err = d.DownloadCandles(ctx, strings.Split(symbols, ","), args.PeriodType(period), fromTime, tillTime)
+	case "trades":
+		results, err = d.DownloadTrades(ctx, strings.Split(symbols, ","), fromTime, tillTime)
+	default:
+		return fmt.Errorf("invalid kind: %q", kind)
+	}
+	if err != nil {
+		return err
+	}
+	failed := false
+	for _, result := range results {
+		if result.Err != nil {
+			failed = true
+			fmt.Fprintf(os.Stderr, "%v: %v\n", result.Symbol, result.Err)
+			continue
+		}
+		fmt.Printf("%v: %v rows in %v\n", result.Symbol, result.Rows, result.Path)
+		for _, gap := range result.Gaps {
+			fmt.Printf("%v: no candles from %v till %v\n", result.Symbol, gap.From.Format(time.RFC3339), gap.Till.Format(time.RFC3339))
+		}
+	}
+	if failed {
+		return fmt.Errorf("some downloads failed, run again to resume them")
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cryptomarket/cryptomarket-go/args"
+	"github.com/cryptomarket/cryptomarket-go/models"
+)
+
+var baseTime = time.Date(2021, 1, 20, 20, 0, 0, 0, time.UTC)
+
+// fakeSource serves a candle per minute, and records the start of the requests.
+type fakeSource struct {
+	minutes int
+	froms   []string
+}
+
+func (source *fakeSource) GetCandlesOfSymbol(ctx context.Context, arguments ...args.Argument) ([]models.Candle, error) {
+	params := make(map[string]interface{})
+	for _, argument := range arguments {
+		argument(params)
+	}
+	source.froms = append(source.froms, params["from"].(string))
+	from, _ := time.Parse(time.RFC3339, params["from"].(string))
+	till, _ := time.Parse(time.RFC3339, params["till"].(string))
+	candles := make([]models.Candle, 0)
+	for minute := 0; minute < source.minutes; minute++ {
+		ts := baseTime.Add(time.Duration(minute) * time.Minute)
+		if ts.Before(from) || ts.After(till) || len(candles) == params["limit"].(int) {
+			continue
+		}
+		candles = append(candles, models.Candle{Timestamp: ts.Format(time.RFC3339), Open: "1", Close: "1", Min: "1", Max: "1", Volume: "1", VolumeQuote: "1"})
+	}
+	return candles, nil
+}
+
+func (source *fakeSource) GetTradesOfSymbol(ctx context.Context, arguments ...args.Argument) ([]models.PublicTrade, error) {
+	return []models.PublicTrade{}, nil
+}
+
+func TestRunResumes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cryptomkt-download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	source := &fakeSource{minutes: 35}
+	from := baseTime.Format(time.RFC3339)
+	middle := baseTime.Add(20 * time.Minute).Format(time.RFC3339)
+	till := baseTime.Add(35 * time.Minute).Format(time.RFC3339)
+	if err = run(source, "candles", "EOSETH", "M1", from, middle, dir, "csv", 1, 0); err != nil {
+		t.Fatal(err)
+	}
+	source.froms = nil
+	if err = run(source, "candles", "EOSETH", "M1", from, till, dir, "csv", 1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if len(source.froms) == 0 || !strings.HasPrefix(source.froms[0], "2021-01-20T20:20:00") {
+		t.Fatalf("expected to resume at the checkpoint, requested from %v", source.froms)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "candles_EOSETH_M1.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 36 {
+		t.Fatalf("expected a header and 35 candles, got %v lines", len(lines))
+	}
+	for idx, line := range lines[1:] {
+		if !strings.HasPrefix(line, baseTime.Add(time.Duration(idx)*time.Minute).Format(time.RFC3339)) {
+			t.Fatalf("wrong line %v: %v", idx, line)
+		}
+	}
+}
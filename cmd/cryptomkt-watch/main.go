@@ -0,0 +1,166 @@
+// Command cryptomkt-watch watches live market and account data over the
+// websocket clients, either as continuously updating terminal views or as
+// JSONL on stdout, one record per line, for piping into other tools.
+//
+// Account modes read the credentials like the cryptomkt command, from the
+// CRYPTOMKT_API_KEY and CRYPTOMKT_API_SECRET environment variables or from a
+// profile of a credentials file, see the credentials package.
+//
+// Usage:
+//
+//	cryptomkt-watch [-jsonl] [-rows n] ticker symbol...
+//	cryptomkt-watch [-jsonl] [-rows n] book symbol
+//	cryptomkt-watch [-jsonl] [-rows n] trades symbol...
+//	cryptomkt-watch [-jsonl] [-rows n] [-period M1] candles symbol
+//	cryptomkt-watch [-jsonl] [-rows n] reports|transactions|balance
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/cryptomarket/cryptomarket-go/args"
+	"github.com/cryptomarket/cryptomarket-go/credentials"
+	"github.com/cryptomarket/cryptomarket-go/models"
+	"github.com/cryptomarket/cryptomarket-go/websocket"
+)
+
+type options struct {
+	mode        string
+	symbols     []string
+	rows        int
+	period      string
+	credentials credentials.Provider
+}
+
+func main() {
+	jsonl := flag.Bool("jsonl", false, "emit JSONL to stdout instead of a terminal view")
+	rows := flag.Int("rows", 20, "rows of the views, and levels per side of the book")
+	period := flag.String("period", string(args.PeriodType1Minutes), "period of the candles")
+	refresh := flag.Duration("refresh", 100*time.Millisecond, "minimum time between redraws of the views")
+	configPath := flag.String("config", credentials.DefaultPath(), "path of the credentials file")
+	profile := flag.String("profile", "", "profile of the credentials file, default is $"+credentials.EnvProfile+" or "+credentials.DefaultProfile)
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: cryptomkt-watch [flags] ticker|book|trades|candles|reports|transactions|balance [symbol...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		cancel()
+	}()
+
+	opts := options{mode: flag.Arg(0), symbols: flag.Args()[1:], rows: *rows, period: *period}
+	opts.credentials = credentials.Chain(credentials.Env(), credentials.File(*configPath, *profile))
+	s := &screen{out: os.Stdout, jsonl: *jsonl, refresh: *refresh}
+	if err := run(ctx, s, opts); err != nil {
+		fmt.Fprintln(os.Stderr, "cryptomkt-watch:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, s *screen, opts options) error {
+	switch opts.mode {
+	case "ticker", "book", "trades", "candles":
+		if len(opts.symbols) == 0 {
+			return fmt.Errorf("%v: expected symbols", opts.mode)
+		}
+		if (opts.mode == "book" || opts.mode == "candles") && len(opts.symbols) != 1 {
+			return fmt.Errorf("%v: expected a single symbol", opts.mode)
+		}
+		client, err := websocket.NewPublicClient()
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+		return watchMarket(ctx, s, client, opts)
+	case "reports":
+		client, err := websocket.NewTradingClientFromProvider(ctx, opts.credentials)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+		feedCh, err := client.SubscribeToReports()
+		if err != nil {
+			return err
+		}
+		return s.run(ctx, forward(feedCh), newListView(opts.rows, reportHeader, reportRow))
+	case "transactions", "balance":
+		client, err := websocket.NewAccountClientFromProvider(ctx, opts.credentials)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+		if opts.mode == "balance" {
+			feedCh, err := client.SubscribeToBalance()
+			if err != nil {
+				return err
+			}
+			return s.run(ctx, forward(feedCh), &balanceView{})
+		}
+		feedCh, err := client.SubscribeToTransactions()
+		if err != nil {
+			return err
+		}
+		return s.run(ctx, forward(feedCh), newListView(opts.rows, transactionHeader, transactionRow))
+	}
+	return fmt.Errorf("unknown mode %q", opts.mode)
+}
+
+func watchMarket(ctx context.Context, s *screen, client *websocket.PublicClient, opts options) error {
+	switch opts.mode {
+	case "ticker":
+		events := make(chan interface{})
+		for _, symbol := range opts.symbols {
+			feedCh, err := client.SubscribeToTicker(args.Symbol(symbol))
+			if err != nil {
+				return err
+			}
+			go func() {
+				for ticker := range feedCh {
+					events <- ticker
+				}
+			}()
+		}
+		return s.run(ctx, events, &tickerView{tickers: make(map[string]models.Ticker)})
+	case "book":
+		feedCh, err := client.SubscribeToOrderbook(args.Symbol(opts.symbols[0]), args.Depth(opts.rows))
+		if err != nil {
+			return err
+		}
+		return s.run(ctx, forward(feedCh), &bookView{})
+	case "trades":
+		events := make(chan interface{})
+		for _, symbol := range opts.symbols {
+			symbol := symbol
+			feedCh, err := client.SubscribeToTrades(args.Symbol(symbol))
+			if err != nil {
+				return err
+			}
+			go func() {
+				for trades := range feedCh {
+					events <- symbolTrades(symbol, trades)
+				}
+			}()
+		}
+		return s.run(ctx, events, newTapeView(opts.rows))
+	}
+	feedCh, err := client.SubscribeToCandles(args.Symbol(opts.symbols[0]), args.Period(args.PeriodType(opts.period)), args.Limit(opts.rows))
+	if err != nil {
+		return err
+	}
+	return s.run(ctx, forward(feedCh), newCandleView(opts.rows))
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"text/tabwriter"
+	"time"
+)
+
+// clearScreen moves the cursor home and clears the terminal.
+const clearScreen = "\x1b[H\x1b[2J"
+
+// view is a terminal view updated by the events of a feed.
+type view interface {
+	update(event interface{})
+	draw(w io.Writer)
+}
+
+// screen shows the events of a feed, either as a view redrawn at most once
+// per refresh, or as JSONL.
+type screen struct {
+	out     io.Writer
+	jsonl   bool
+	refresh time.Duration
+}
+
+// forward forwards the values of a typed feed channel as events.
+func forward(feedCh interface{}) <-chan interface{} {
+	events := make(chan interface{})
+	go func() {
+		defer close(events)
+		ch := reflect.ValueOf(feedCh)
+		for {
+			value, ok := ch.Recv()
+			if !ok {
+				return
+			}
+			events <- value.Interface()
+		}
+	}()
+	return events
+}
+
+// run shows the events until the context is done or the feed ends.
+func (s *screen) run(ctx context.Context, events <-chan interface{}, v view) error {
+	if s.jsonl {
+		return s.emit(ctx, events)
+	}
+	ticker := time.NewTicker(s.refresh)
+	defer ticker.Stop()
+	dirty := false
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				return fmt.Errorf("feed closed")
+			}
+			v.update(event)
+			dirty = true
+		case <-ticker.C:
+			if dirty {
+				s.draw(v)
+				dirty = false
+			}
+		}
+	}
+}
+
+func (s *screen) draw(v view) {
+	var buffer bytes.Buffer
+	buffer.WriteString(clearScreen)
+	writer := tabwriter.NewWriter(&buffer, 0, 0, 2, ' ', tabwriter.AlignRight)
+	v.draw(writer)
+	writer.Flush()
+	s.out.Write(buffer.Bytes())
+}
+
+// emit writes the events as JSONL. Slices are written one element per line.
+func (s *screen) emit(ctx context.Context, events <-chan interface{}) error {
+	encoder := json.NewEncoder(s.out)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				return fmt.Errorf("feed closed")
+			}
+			value := reflect.ValueOf(event)
+			if value.Kind() != reflect.Slice {
+				if err := encoder.Encode(event); err != nil {
+					return err
+				}
+				continue
+			}
+			for idx := 0; idx < value.Len(); idx++ {
+				if err := encoder.Encode(value.Index(idx).Interface()); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
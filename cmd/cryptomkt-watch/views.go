@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/cryptomarket/cryptomarket-go/models"
+	"github.com/cryptomarket/cryptomarket-go/orderbook"
+)
+
+// tickerView shows the last ticker of each symbol.
+type tickerView struct {
+	tickers map[string]models.Ticker
+}
+
+func (v *tickerView) update(event interface{}) {
+	ticker := event.(models.Ticker)
+	v.tickers[ticker.Symbol] = ticker
+}
+
+func (v *tickerView) draw(w io.Writer) {
+	symbols := make([]string, 0, len(v.tickers))
+	for symbol := range v.tickers {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	fmt.Fprintln(w, "SYMBOL\tBID\tASK\tLAST\tLOW\tHIGH\tVOLUME\t")
+	for _, symbol := range symbols {
+		t := v.tickers[symbol]
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t\n", t.Symbol, t.Bid, t.Ask, t.Last, t.Low, t.High, t.Volume)
+	}
+}
+
+// bookView shows the top of an order book as a ladder, asks above bids.
+type bookView struct {
+	book models.OrderBook
+}
+
+func (v *bookView) update(event interface{}) {
+	v.book = event.(models.OrderBook)
+}
+
+func (v *bookView) draw(w io.Writer) {
+	fmt.Fprintf(w, "%v\t%v\t\n", v.book.Symbol, v.book.Timestamp)
+	fmt.Fprintln(w, "PRICE\tSIZE\t")
+	for idx := len(v.book.Ask) - 1; idx >= 0; idx-- {
+		fmt.Fprintf(w, "%v\t%v\t\n", v.book.Ask[idx].Price, v.book.Ask[idx].Size)
+	}
+	if spread, err := orderbook.Spread(&v.book); err == nil {
+		fmt.Fprintf(w, "spread %v\t\t\n", spread)
+	} else {
+		fmt.Fprintln(w, "-\t\t")
+	}
+	for _, level := range v.book.Bid {
+		fmt.Fprintf(w, "%v\t%v\t\n", level.Price, level.Size)
+	}
+}
+
+// trade is a public trade with its symbol.
+type trade struct {
+	Symbol string `json:"symbol"`
+	models.PublicTrade
+}
+
+func symbolTrades(symbol string, trades []models.PublicTrade) []trade {
+	result := make([]trade, len(trades))
+	for idx, publicTrade := range trades {
+		result[idx] = trade{Symbol: symbol, PublicTrade: publicTrade}
+	}
+	return result
+}
+
+// tapeView shows the last trades, newest first.
+type tapeView struct {
+	size   int
+	trades []trade
+}
+
+func newTapeView(size int) *tapeView {
+	return &tapeView{size: size}
+}
+
+func (v *tapeView) update(event interface{}) {
+	for _, t := range event.([]trade) {
+		v.trades = append([]trade{t}, v.trades...)
+	}
+	if len(v.trades) > v.size {
+		v.trades = v.trades[:v.size]
+	}
+}
+
+func (v *tapeView) draw(w io.Writer) {
+	fmt.Fprintln(w, "TIME\tSYMBOL\tSIDE\tPRICE\tQUANTITY\t")
+	for _, t := range v.trades {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t\n", t.Timestamp, t.Symbol, t.Side, t.Price, t.Quantity)
+	}
+}
+
+// candleView shows the last candles, newest first, updating the open one.
+type candleView struct {
+	size    int
+	candles []models.Candle
+}
+
+func newCandleView(size int) *candleView {
+	return &candleView{size: size}
+}
+
+func (v *candleView) update(event interface{}) {
+	for _, candle := range event.([]models.Candle) {
+		idx := sort.Search(len(v.candles), func(i int) bool { return v.candles[i].Timestamp <= candle.Timestamp })
+		if idx < len(v.candles) && v.candles[idx].Timestamp == candle.Timestamp {
+			v.candles[idx] = candle
+			continue
+		}
+		v.candles = append(v.candles, models.Candle{})
+		copy(v.candles[idx+1:], v.candles[idx:])
+		v.candles[idx] = candle
+	}
+	if len(v.candles) > v.size {
+		v.candles = v.candles[:v.size]
+	}
+}
+
+func (v *candleView) draw(w io.Writer) {
+	fmt.Fprintln(w, "TIME\tOPEN\tMAX\tMIN\tCLOSE\tVOLUME\t")
+	for _, c := range v.candles {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t\n", c.Timestamp, c.Open, c.Max, c.Min, c.Close, c.Volume)
+	}
+}
+
+// listView shows the last events, newest first, a row each.
+type listView struct {
+	size   int
+	header string
+	row    func(event interface{}) string
+	rows   []string
+}
+
+func newListView(size int, header string, row func(event interface{}) string) *listView {
+	return &listView{size: size, header: header, row: row}
+}
+
+func (v *listView) update(event interface{}) {
+	v.rows = append([]string{v.row(event)}, v.rows...)
+	if len(v.rows) > v.size {
+		v.rows = v.rows[:v.size]
+	}
+}
+
+func (v *listView) draw(w io.Writer) {
+	fmt.Fprintln(w, v.header)
+	for _, row := range v.rows {
+		fmt.Fprintln(w, row)
+	}
+}
+
+const reportHeader = "TIME\tSYMBOL\tCLIENT ORDER ID\tTYPE\tSIDE\tSTATUS\tPRICE\tQUANTITY\tFILLED\t"
+
+func reportRow(event interface{}) string {
+	r := event.(models.Report)
+	return fmt.Sprintf("%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t", r.UpdatedAt, r.Symbol, r.ClientOrderID, r.ReportType, r.Side, r.Status, r.Price, r.Quantity, r.CumQuantity)
+}
+
+const transactionHeader = "TIME\tID\tTYPE\tSTATUS\tCURRENCY\tAMOUNT\tFEE\t"
+
+func transactionRow(event interface{}) string {
+	t := event.(models.Transaction)
+	return fmt.Sprintf("%v\t%v\t%v\t%v\t%v\t%v\t%v\t", t.UpdatedAt, t.ID, t.Type, t.Status, t.Currency, t.Amount, t.Fee)
+}
+
+// balanceView shows the last balance, without empty currencies.
+type balanceView struct {
+	balance []models.Balance
+}
+
+func (v *balanceView) update(event interface{}) {
+	v.balance = event.([]models.Balance)
+}
+
+func (v *balanceView) draw(w io.Writer) {
+	fmt.Fprintln(w, "CURRENCY\tAVAILABLE\tRESERVED\t")
+	for _, b := range v.balance {
+		if isZero(b.Available) && isZero(b.Reserved) {
+			continue
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\t\n", b.Currency, b.Available, b.Reserved)
+	}
+}
+
+func isZero(value string) bool {
+	for _, r := range value {
+		if r != '0' && r != '.' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/cryptomarket/cryptomarket-go/models"
+)
+
+func TestCandleView(t *testing.T) {
+	v := newCandleView(2)
+	v.update([]models.Candle{{Timestamp: "2021-01-20T20:00:00.000Z"}, {Timestamp: "2021-01-20T20:01:00.000Z", Close: "1"}})
+	v.update([]models.Candle{{Timestamp: "2021-01-20T20:01:00.000Z", Close: "2"}})
+	v.update([]models.Candle{{Timestamp: "2021-01-20T20:02:00.000Z"}})
+	timestamps := make([]string, 0)
+	for _, candle := range v.candles {
+		timestamps = append(timestamps, candle.Timestamp)
+	}
+	if !reflect.DeepEqual(timestamps, []string{"2021-01-20T20:02:00.000Z", "2021-01-20T20:01:00.000Z"}) {
+		t.Fatalf("wrong candles: %v", timestamps)
+	}
+	if v.candles[1].Close != "2" {
+		t.Fatalf("open candle not updated: %+v", v.candles[1])
+	}
+}
+
+func TestTapeView(t *testing.T) {
+	v := newTapeView(3)
+	v.update(symbolTrades("EOSETH", []models.PublicTrade{{ID: 1}, {ID: 2}}))
+	v.update(symbolTrades("ETHBTC", []models.PublicTrade{{ID: 3}, {ID: 4}}))
+	ids := make([]int64, 0)
+	for _, trade := range v.trades {
+		ids = append(ids, trade.ID)
+	}
+	if !reflect.DeepEqual(ids, []int64{4, 3, 2}) || v.trades[0].Symbol != "ETHBTC" {
+		t.Fatalf("wrong tape: %+v", v.trades)
+	}
+}
+
+func TestEmit(t *testing.T) {
+	var buffer bytes.Buffer
+	s := &screen{out: &buffer, jsonl: true}
+	feedCh := make(chan []trade, 1)
+	feedCh <- symbolTrades("EOSETH", []models.PublicTrade{{ID: 1, Price: "0.1"}, {ID: 2, Price: "0.2"}})
+	close(feedCh)
+	if err := s.run(context.Background(), forward(feedCh), nil); err == nil {
+		t.Fatal("expected the end of the feed")
+	}
+	expected := `{"symbol":"EOSETH","id":1,"price":"0.1","quantity":"","side":"","timestamp":""}
+{"symbol":"EOSETH","id":2,"price":"0.2","quantity":"","side":"","timestamp":""}
+`
+	if buffer.String() != expected {
+		t.Fatalf("wrong jsonl:\n%v\nexpected\n%v", buffer.String(), expected)
+	}
+}
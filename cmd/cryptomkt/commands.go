@@ -0,0 +1,420 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/cryptomarket/cryptomarket-go/args"
+	"github.com/cryptomarket/cryptomarket-go/models"
+)
+
+var commands = map[string]command{
+	"currencies":      {usage: "currencies [currency]", summary: "currencies of the exchange", run: runCurrencies},
+	"symbols":         {usage: "symbols [symbol]", summary: "symbols of the exchange", run: runSymbols},
+	"ticker":          {usage: "ticker [symbol...]", summary: "tickers of the symbols, or of all of them", run: runTicker},
+	"book":            {usage: "book [-limit n] symbol", summary: "order book of a symbol", run: runBook},
+	"candles":         {usage: "candles [-period M30] [-limit n] [-from t] [-till t] symbol", summary: "candles of a symbol", run: runCandles},
+	"balance":         {usage: "balance [-account]", summary: "trading balance, or account balance", private: true, run: runBalance},
+	"orders":          {usage: "orders list|create|cancel [arguments]", summary: "active orders", private: true, run: runOrders},
+	"history":         {usage: "history orders|trades [-symbol s] [-from t] [-till t] [-limit n]", summary: "order or trade history", private: true, run: runHistory},
+	"transfer":        {usage: "transfer -currency c -amount a -to trading|account", summary: "transfer between the trading and account balances", private: true, run: runTransfer},
+	"deposit-address": {usage: "deposit-address [-new] currency", summary: "deposit address of a currency", private: true, run: runDepositAddress},
+	"withdraw":        {usage: "withdraw -currency c -amount a -address addr [-yes]", summary: "withdraw crypto, after confirmation", private: true, run: runWithdraw},
+}
+
+// parse parses flags interleaved with positional arguments, returning the positional ones.
+func parse(flags *flag.FlagSet, argv []string) ([]string, error) {
+	flags.SetOutput(ioutil.Discard)
+	positional := make([]string, 0)
+	for {
+		if err := flags.Parse(argv); err != nil {
+			if err == flag.ErrHelp {
+				flags.SetOutput(os.Stderr)
+				flags.PrintDefaults()
+			}
+			return nil, fmt.Errorf("%v: %v", flags.Name(), err)
+		}
+		if flags.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, flags.Arg(0))
+		argv = flags.Args()[1:]
+	}
+}
+
+func runCurrencies(ctx context.Context, a *app, argv []string) error {
+	positional, err := parse(flag.NewFlagSet("currencies", flag.ContinueOnError), argv)
+	if err != nil {
+		return err
+	}
+	if len(positional) == 1 {
+		currency, err := a.client.GetCurrency(ctx, args.Currency(positional[0]))
+		if err != nil {
+			return err
+		}
+		return render(a.out, a.format, currency)
+	}
+	currencies, err := a.client.GetCurrencies(ctx)
+	if err != nil {
+		return err
+	}
+	return render(a.out, a.format, currencies)
+}
+
+func runSymbols(ctx context.Context, a *app, argv []string) error {
+	positional, err := parse(flag.NewFlagSet("symbols", flag.ContinueOnError), argv)
+	if err != nil {
+		return err
+	}
+	if len(positional) == 1 {
+		symbol, err := a.client.GetSymbol(ctx, args.Symbol(positional[0]))
+		if err != nil {
+			return err
+		}
+		return render(a.out, a.format, symbol)
+	}
+	symbols, err := a.client.GetSymbols(ctx)
+	if err != nil {
+		return err
+	}
+	return render(a.out, a.format, symbols)
+}
+
+func runTicker(ctx context.Context, a *app, argv []string) error {
+	positional, err := parse(flag.NewFlagSet("ticker", flag.ContinueOnError), argv)
+	if err != nil {
+		return err
+	}
+	arguments := make([]args.Argument, 0)
+	if len(positional) > 0 {
+		arguments = append(arguments, args.Symbols(positional))
+	}
+	tickers, err := a.client.GetTickers(ctx, arguments...)
+	if err != nil {
+		return err
+	}
+	return render(a.out, a.format, tickers)
+}
+
+// bookLevel is a row of the ladder of an order book.
+type bookLevel struct {
+	Side  string `json:"side"`
+	Price string `json:"price"`
+	Size  string `json:"size"`
+}
+
+// ladder returns the levels of a book with the asks on top, best prices in the middle.
+func ladder(book *models.OrderBook) []bookLevel {
+	levels := make([]bookLevel, 0, len(book.Ask)+len(book.Bid))
+	for idx := len(book.Ask) - 1; idx >= 0; idx-- {
+		levels = append(levels, bookLevel{Side: "ask", Price: book.Ask[idx].Price, Size: book.Ask[idx].Size})
+	}
+	for _, level := range book.Bid {
+		levels = append(levels, bookLevel{Side: "bid", Price: level.Price, Size: level.Size})
+	}
+	return levels
+}
+
+func runBook(ctx context.Context, a *app, argv []string) error {
+	flags := flag.NewFlagSet("book", flag.ContinueOnError)
+	limit := flags.Int("limit", 10, "levels per side, 0 for the full book")
+	positional, err := parse(flags, argv)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("book: expected a symbol")
+	}
+	book, err := a.client.GetOrderbook(ctx, args.Symbol(positional[0]), args.Limit(*limit))
+	if err != nil {
+		return err
+	}
+	if a.format == formatJSON {
+		return render(a.out, a.format, book)
+	}
+	return render(a.out, a.format, ladder(book))
+}
+
+func runCandles(ctx context.Context, a *app, argv []string) error {
+	flags := flag.NewFlagSet("candles", flag.ContinueOnError)
+	period := flags.String("period", string(args.PeriodType30Minutes), "period of the candles")
+	limit := flags.Int("limit", 100, "number of candles")
+	from := flags.String("from", "", "start of the interval")
+	till := flags.String("till", "", "end of the interval")
+	positional, err := parse(flags, argv)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("candles: expected a symbol")
+	}
+	arguments := []args.Argument{args.Symbol(positional[0]), args.Period(args.PeriodType(*period)), args.Limit(*limit)}
+	arguments = append(arguments, intervalArguments(*from, *till)...)
+	candles, err := a.client.GetCandlesOfSymbol(ctx, arguments...)
+	if err != nil {
+		return err
+	}
+	return render(a.out, a.format, candles)
+}
+
+func intervalArguments(from, till string) []args.Argument {
+	arguments := make([]args.Argument, 0)
+	if from != "" {
+		arguments = append(arguments, args.From(from))
+	}
+	if till != "" {
+		arguments = append(arguments, args.Till(till))
+	}
+	return arguments
+}
+
+func runBalance(ctx context.Context, a *app, argv []string) error {
+	flags := flag.NewFlagSet("balance", flag.ContinueOnError)
+	account := flags.Bool("account", false, "show the account balance instead of the trading balance")
+	if _, err := parse(flags, argv); err != nil {
+		return err
+	}
+	var balance []models.Balance
+	var err error
+	if *account {
+		balance, err = a.client.GetAccountBalance(ctx)
+	} else {
+		balance, err = a.client.GetTradingBalance(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	return render(a.out, a.format, balance)
+}
+
+func runOrders(ctx context.Context, a *app, argv []string) error {
+	if len(argv) == 0 {
+		return fmt.Errorf("orders: expected list, create or cancel")
+	}
+	switch argv[0] {
+	case "list":
+		return runOrdersList(ctx, a, argv[1:])
+	case "create":
+		return runOrdersCreate(ctx, a, argv[1:])
+	case "cancel":
+		return runOrdersCancel(ctx, a, argv[1:])
+	}
+	return fmt.Errorf("orders: unknown subcommand %q", argv[0])
+}
+
+func runOrdersList(ctx context.Context, a *app, argv []string) error {
+	flags := flag.NewFlagSet("orders list", flag.ContinueOnError)
+	symbol := flags.String("symbol", "", "filter by symbol")
+	if _, err := parse(flags, argv); err != nil {
+		return err
+	}
+	arguments := make([]args.Argument, 0)
+	if *symbol != "" {
+		arguments = append(arguments, args.Symbol(*symbol))
+	}
+	orders, err := a.client.GetActiveOrders(ctx, arguments...)
+	if err != nil {
+		return err
+	}
+	return render(a.out, a.format, orders)
+}
+
+func runOrdersCreate(ctx context.Context, a *app, argv []string) error {
+	flags := flag.NewFlagSet("orders create", flag.ContinueOnError)
+	symbol := flags.String("symbol", "", "symbol of the order")
+	side := flags.String("side", "", "buy or sell")
+	quantity := flags.String("quantity", "", "quantity of the order")
+	price := flags.String("price", "", "limit price")
+	stopPrice := flags.String("stop-price", "", "stop price")
+	orderType := flags.String("type", string(args.OrderTypeLimit), "limit, market, stopLimit or stopMarket")
+	timeInForce := flags.String("tif", "", "time in force, GTC, IOC, FOK, Day or GTD")
+	expireTime := flags.String("expire-time", "", "expire time of GTD orders")
+	clientOrderID := flags.String("client-order-id", "", "client order id, generated by the exchange if empty")
+	postOnly := flags.Bool("post-only", false, "cancel the order if it would take liquidity")
+	if _, err := parse(flags, argv); err != nil {
+		return err
+	}
+	if *symbol == "" || *side == "" || *quantity == "" {
+		return fmt.Errorf("orders create: -symbol, -side and -quantity are required")
+	}
+	arguments := []args.Argument{
+		args.Symbol(*symbol),
+		args.Side(args.SideType(*side)),
+		args.Quantity(*quantity),
+		args.Type(args.OrderType(*orderType)),
+	}
+	optionals := []struct {
+		value    string
+		argument func(string) args.Argument
+	}{
+		{*price, args.Price},
+		{*stopPrice, args.StopPrice},
+		{*timeInForce, func(val string) args.Argument { return args.TimeInForce(args.TimeInForceType(val)) }},
+		{*expireTime, args.ExpireTime},
+		{*clientOrderID, args.ClientOrderID},
+	}
+	for _, optional := range optionals {
+		if optional.value != "" {
+			arguments = append(arguments, optional.argument(optional.value))
+		}
+	}
+	if *postOnly {
+		arguments = append(arguments, args.PostOnly(true))
+	}
+	order, err := a.client.CreateOrder(ctx, arguments...)
+	if err != nil {
+		return err
+	}
+	return render(a.out, a.format, order)
+}
+
+func runOrdersCancel(ctx context.Context, a *app, argv []string) error {
+	flags := flag.NewFlagSet("orders cancel", flag.ContinueOnError)
+	all := flags.Bool("all", false, "cancel all the active orders")
+	positional, err := parse(flags, argv)
+	if err != nil {
+		return err
+	}
+	if *all {
+		if len(positional) > 0 {
+			return fmt.Errorf("orders cancel: -all takes no client order ids")
+		}
+		orders, err := a.client.CancelAllOrders(ctx)
+		if err != nil {
+			return err
+		}
+		return render(a.out, a.format, orders)
+	}
+	if len(positional) == 0 {
+		return fmt.Errorf("orders cancel: expected client order ids, or -all")
+	}
+	orders := make([]models.Order, 0, len(positional))
+	for _, clientOrderID := range positional {
+		order, err := a.client.CancelOrder(ctx, args.ClientOrderID(clientOrderID))
+		if err != nil {
+			return fmt.Errorf("%v: %v", clientOrderID, err)
+		}
+		orders = append(orders, *order)
+	}
+	return render(a.out, a.format, orders)
+}
+
+func runHistory(ctx context.Context, a *app, argv []string) error {
+	if len(argv) == 0 || (argv[0] != "orders" && argv[0] != "trades") {
+		return fmt.Errorf("history: expected orders or trades")
+	}
+	flags := flag.NewFlagSet("history "+argv[0], flag.ContinueOnError)
+	symbol := flags.String("symbol", "", "filter by symbol")
+	from := flags.String("from", "", "start of the interval")
+	till := flags.String("till", "", "end of the interval")
+	limit := flags.Int("limit", 100, "number of results")
+	if _, err := parse(flags, argv[1:]); err != nil {
+		return err
+	}
+	arguments := append(intervalArguments(*from, *till), args.Limit(*limit))
+	if *symbol != "" {
+		arguments = append(arguments, args.Symbol(*symbol))
+	}
+	if argv[0] == "orders" {
+		orders, err := a.client.GetOrderHistory(ctx, arguments...)
+		if err != nil {
+			return err
+		}
+		return render(a.out, a.format, orders)
+	}
+	trades, err := a.client.GetTradeHistory(ctx, arguments...)
+	if err != nil {
+		return err
+	}
+	return render(a.out, a.format, trades)
+}
+
+func runTransfer(ctx context.Context, a *app, argv []string) error {
+	flags := flag.NewFlagSet("transfer", flag.ContinueOnError)
+	currency := flags.String("currency", "", "currency to transfer")
+	amount := flags.String("amount", "", "amount to transfer")
+	to := flags.String("to", "", "destination balance, trading or account")
+	if _, err := parse(flags, argv); err != nil {
+		return err
+	}
+	if *currency == "" || *amount == "" {
+		return fmt.Errorf("transfer: -currency and -amount are required")
+	}
+	var transaction *models.Transaction
+	var err error
+	switch *to {
+	case "trading":
+		transaction, err = a.client.TransferMoneyFromAccountToTradingBalance(ctx, args.Currency(*currency), args.Amount(*amount))
+	case "account":
+		transaction, err = a.client.TransferMoneyFromTradingToAccountBalance(ctx, args.Currency(*currency), args.Amount(*amount))
+	default:
+		return fmt.Errorf("transfer: -to must be trading or account")
+	}
+	if err != nil {
+		return err
+	}
+	return render(a.out, a.format, transaction)
+}
+
+func runDepositAddress(ctx context.Context, a *app, argv []string) error {
+	flags := flag.NewFlagSet("deposit-address", flag.ContinueOnError)
+	create := flags.Bool("new", false, "create a new address")
+	positional, err := parse(flags, argv)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("deposit-address: expected a currency")
+	}
+	var address *models.CryptoAddress
+	if *create {
+		address, err = a.client.CreateDepositCryptoAddress(ctx, args.Currency(positional[0]))
+	} else {
+		address, err = a.client.GetDepositCryptoAddress(ctx, args.Currency(positional[0]))
+	}
+	if err != nil {
+		return err
+	}
+	return render(a.out, a.format, address)
+}
+
+func runWithdraw(ctx context.Context, a *app, argv []string) error {
+	flags := flag.NewFlagSet("withdraw", flag.ContinueOnError)
+	currency := flags.String("currency", "", "currency to withdraw")
+	amount := flags.String("amount", "", "amount to withdraw")
+	address := flags.String("address", "", "destination address")
+	paymentID := flags.String("payment-id", "", "payment id of the destination, if needed")
+	includeFee := flags.Bool("include-fee", false, "take the fee from the amount")
+	yes := flags.Bool("yes", false, "withdraw without confirmation")
+	if _, err := parse(flags, argv); err != nil {
+		return err
+	}
+	if *currency == "" || *amount == "" || *address == "" {
+		return fmt.Errorf("withdraw: -currency, -amount and -address are required")
+	}
+	if !*yes {
+		fee, err := a.client.GetEstimatesWithdrawFee(ctx, args.Currency(*currency), args.Amount(*amount))
+		if err != nil {
+			return err
+		}
+		question := fmt.Sprintf("withdraw %v %v to %v, with an estimated fee of %v %v?", *amount, *currency, *address, fee, *currency)
+		if !a.confirm(question) {
+			return fmt.Errorf("withdraw: canceled")
+		}
+	}
+	arguments := []args.Argument{args.Currency(*currency), args.Amount(*amount), args.Address(*address)}
+	if *paymentID != "" {
+		arguments = append(arguments, args.PaymentID(*paymentID))
+	}
+	if *includeFee {
+		arguments = append(arguments, args.IncludeFee(true))
+	}
+	transaction, err := a.client.WithdrawCrypto(ctx, arguments...)
+	if err != nil {
+		return err
+	}
+	return render(a.out, a.format, transaction)
+}
@@ -0,0 +1,120 @@
+// Command cryptomkt exposes the methods of the rest client as subcommands,
+// to check markets and balances or to manage orders from a shell.
+//
+// Credentials are read from the CRYPTOMKT_API_KEY and CRYPTOMKT_API_SECRET
+// environment variables or, when unset, from a profile of a credentials file,
+// see the credentials package.
+//
+// Usage:
+//
+//	cryptomkt [-o table|json|csv] [-config path] [-profile name] <command> [arguments]
+//
+// Run cryptomkt help for the list of commands.
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/cryptomarket/cryptomarket-go/credentials"
+	"github.com/cryptomarket/cryptomarket-go/rest"
+)
+
+// app is the state shared by the commands.
+type app struct {
+	out        io.Writer
+	in         *bufio.Reader
+	format     string
+	configPath string
+	profile    string
+	client     *rest.Client
+}
+
+// command is a subcommand of the tool.
+type command struct {
+	usage   string
+	summary string
+	private bool // requires credentials
+	run     func(ctx context.Context, app *app, argv []string) error
+}
+
+func main() {
+	flags := flag.NewFlagSet("cryptomkt", flag.ExitOnError)
+	format := flags.String("o", formatTable, "output format, table, json or csv")
+	configPath := flags.String("config", credentials.DefaultPath(), "path of the credentials file")
+	profile := flags.String("profile", "", "profile of the credentials file, default is $"+credentials.EnvProfile+" or "+credentials.DefaultProfile)
+	flags.Usage = func() { printUsage(os.Stderr) }
+	flags.Parse(os.Args[1:])
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		cancel()
+	}()
+
+	a := &app{out: os.Stdout, in: bufio.NewReader(os.Stdin), format: *format, configPath: *configPath, profile: *profile}
+	if err := a.run(ctx, flags.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, "cryptomkt:", err)
+		os.Exit(1)
+	}
+}
+
+func (a *app) run(ctx context.Context, argv []string) error {
+	if a.format != formatTable && a.format != formatJSON && a.format != formatCSV {
+		return fmt.Errorf("invalid output format: %q", a.format)
+	}
+	if len(argv) == 0 || argv[0] == "help" || argv[0] == "-h" {
+		printUsage(a.out)
+		return nil
+	}
+	cmd, ok := commands[argv[0]]
+	if !ok {
+		return fmt.Errorf("unknown command %q, run cryptomkt help", argv[0])
+	}
+	if cmd.private {
+		provider := credentials.Chain(credentials.Env(), credentials.File(a.configPath, a.profile))
+		client, err := rest.NewClientFromProvider(ctx, provider)
+		if err != nil {
+			return err
+		}
+		a.client = client
+	} else {
+		a.client = rest.NewClient("", "")
+	}
+	return cmd.run(ctx, a, argv[1:])
+}
+
+// confirm asks a yes or no question on the terminal.
+func (a *app) confirm(question string) bool {
+	fmt.Fprintf(os.Stderr, "%v [y/N] ", question)
+	answer, _ := a.in.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: cryptomkt [-o table|json|csv] [-config path] [-profile name] <command> [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, name := range names {
+		fmt.Fprintf(writer, "  %v\t%v\n", commands[name].usage, commands[name].summary)
+	}
+	writer.Flush()
+}
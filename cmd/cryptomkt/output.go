@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+)
+
+// output formats
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatCSV   = "csv"
+)
+
+// render writes a value, a struct or a slice of structs, in the given format.
+// Tables and CSV have a column per field, named after its json name.
+func render(w io.Writer, format string, value interface{}) error {
+	if format == formatJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	}
+	header, rows := tabulate(value)
+	if format == formatCSV {
+		writer := csv.NewWriter(w)
+		if header != nil {
+			writer.Write(header)
+		}
+		writer.WriteAll(rows)
+		return writer.Error()
+	}
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if header != nil {
+		fmt.Fprintln(writer, strings.ToUpper(strings.Join(header, "\t")))
+	}
+	for _, row := range rows {
+		fmt.Fprintln(writer, strings.Join(row, "\t"))
+	}
+	return writer.Flush()
+}
+
+// tabulate returns the header and rows of a value. Values other than structs
+// and slices of structs are a single cell without header.
+func tabulate(value interface{}) ([]string, [][]string) {
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	switch {
+	case v.Kind() == reflect.Struct:
+		return fieldNames(v.Type()), [][]string{fieldValues(v)}
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Struct:
+		rows := make([][]string, v.Len())
+		for idx := range rows {
+			rows[idx] = fieldValues(v.Index(idx))
+		}
+		return fieldNames(v.Type().Elem()), rows
+	}
+	return nil, [][]string{{fmt.Sprint(v.Interface())}}
+}
+
+func fieldNames(t reflect.Type) []string {
+	names := make([]string, t.NumField())
+	for idx := range names {
+		field := t.Field(idx)
+		names[idx] = field.Name
+		if tag := strings.Split(field.Tag.Get("json"), ",")[0]; tag != "" {
+			names[idx] = tag
+		}
+	}
+	return names
+}
+
+func fieldValues(v reflect.Value) []string {
+	values := make([]string, v.NumField())
+	for idx := range values {
+		field := v.Field(idx)
+		switch field.Kind() {
+		case reflect.Slice, reflect.Struct, reflect.Map, reflect.Ptr:
+			data, _ := json.Marshal(field.Interface())
+			values[idx] = string(data)
+		default:
+			values[idx] = fmt.Sprint(field.Interface())
+		}
+	}
+	return values
+}
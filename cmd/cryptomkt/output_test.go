@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"reflect"
+	"testing"
+
+	"github.com/cryptomarket/cryptomarket-go/models"
+)
+
+func TestRender(t *testing.T) {
+	balance := []models.Balance{
+		{Currency: "ETH", Available: "1.5", Reserved: "0"},
+		{Currency: "BTC", Available: "0.01", Reserved: "0.002"},
+	}
+	var buffer bytes.Buffer
+	if err := render(&buffer, formatCSV, balance); err != nil {
+		t.Fatal(err)
+	}
+	expected := "currency,available,reserved\nETH,1.5,0\nBTC,0.01,0.002\n"
+	if buffer.String() != expected {
+		t.Fatalf("wrong csv:\n%v\nexpected\n%v", buffer.String(), expected)
+	}
+	buffer.Reset()
+	if err := render(&buffer, formatTable, &balance[0]); err != nil {
+		t.Fatal(err)
+	}
+	expected = "CURRENCY  AVAILABLE  RESERVED\nETH       1.5        0\n"
+	if buffer.String() != expected {
+		t.Fatalf("wrong table:\n%v\nexpected\n%v", buffer.String(), expected)
+	}
+	buffer.Reset()
+	order := models.Order{TradesReport: []models.TradeReport{{ID: 1}}}
+	_, rows := tabulate(order)
+	if rows[0][len(rows[0])-1] != `[{"id":1,"price":"","quantity":"","fee":"","timestamp":""}]` {
+		t.Fatalf("nested values should be json: %v", rows[0])
+	}
+	if _, rows = tabulate(true); !reflect.DeepEqual(rows, [][]string{{"true"}}) {
+		t.Fatalf("wrong scalar: %v", rows)
+	}
+}
+
+func TestLadder(t *testing.T) {
+	book := &models.OrderBook{
+		Ask: []models.BookLevel{{Price: "11", Size: "1"}, {Price: "12", Size: "2"}},
+		Bid: []models.BookLevel{{Price: "10", Size: "3"}},
+	}
+	expected := []bookLevel{{"ask", "12", "2"}, {"ask", "11", "1"}, {"bid", "10", "3"}}
+	if levels := ladder(book); !reflect.DeepEqual(levels, expected) {
+		t.Fatalf("wrong ladder: %v", levels)
+	}
+}
+
+func TestParse(t *testing.T) {
+	flags := flag.NewFlagSet("book", flag.ContinueOnError)
+	limit := flags.Int("limit", 10, "")
+	positional, err := parse(flags, []string{"EOSETH", "-limit", "5", "ETHBTC"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *limit != 5 || !reflect.DeepEqual(positional, []string{"EOSETH", "ETHBTC"}) {
+		t.Fatalf("wrong parse: %v %v", *limit, positional)
+	}
+}
@@ -0,0 +1,169 @@
+// Package credentials provides the api keys of the clients from the
+// environment, from profile files or from custom sources, so key management
+// stays out of application code.
+//
+// A profile file is a JSON or YAML file, told apart by its extension, holding
+// named accounts:
+//
+//	profiles:
+//	  default:
+//	    apiKey: "..."
+//	    apiSecret: "..."
+//	  trading-bot:
+//	    apiKey: "..."
+//	    apiSecret: "..."
+//
+// A file with top level apiKey and apiSecret fields is taken as the default profile.
+package credentials
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// environment variables read by the providers of this package
+const (
+	EnvAPIKey    = "CRYPTOMKT_API_KEY"
+	EnvAPISecret = "CRYPTOMKT_API_SECRET"
+	EnvFile      = "CRYPTOMKT_CREDENTIALS_FILE"
+	EnvProfile   = "CRYPTOMKT_PROFILE"
+)
+
+// DefaultProfile is the profile used when none is given.
+const DefaultProfile = "default"
+
+// ErrNotFound is returned, wrapped, by providers without credentials to give.
+// A Chain moves on to its next provider on it.
+var ErrNotFound = errors.New("CryptomarketSDKError: credentials not found")
+
+// Credentials are the api key and secret of an account.
+type Credentials struct {
+	APIKey    string `json:"apiKey"`
+	APISecret string `json:"apiSecret"`
+}
+
+func (credentials Credentials) valid() bool {
+	return credentials.APIKey != "" && credentials.APISecret != ""
+}
+
+// Provider provides credentials.
+type Provider interface {
+	Retrieve(ctx context.Context) (Credentials, error)
+}
+
+// Func is a custom Provider, e.g. reading the credentials from a secrets manager.
+type Func func(ctx context.Context) (Credentials, error)
+
+// Retrieve calls the function.
+func (fn Func) Retrieve(ctx context.Context) (Credentials, error) {
+	return fn(ctx)
+}
+
+// Static returns a provider of fixed credentials.
+func Static(apiKey, apiSecret string) Provider {
+	return Func(func(ctx context.Context) (Credentials, error) {
+		credentials := Credentials{APIKey: apiKey, APISecret: apiSecret}
+		if !credentials.valid() {
+			return Credentials{}, fmt.Errorf("%w: empty static credentials", ErrNotFound)
+		}
+		return credentials, nil
+	})
+}
+
+// Env returns a provider reading the credentials from the CRYPTOMKT_API_KEY
+// and CRYPTOMKT_API_SECRET environment variables.
+func Env() Provider {
+	return EnvVars(EnvAPIKey, EnvAPISecret)
+}
+
+// EnvVars returns a provider reading the credentials from the given environment variables.
+func EnvVars(apiKeyVar, apiSecretVar string) Provider {
+	return Func(func(ctx context.Context) (Credentials, error) {
+		credentials := Credentials{APIKey: os.Getenv(apiKeyVar), APISecret: os.Getenv(apiSecretVar)}
+		if !credentials.valid() {
+			return Credentials{}, fmt.Errorf("%w: %v and %v are not set", ErrNotFound, apiKeyVar, apiSecretVar)
+		}
+		return credentials, nil
+	})
+}
+
+// File returns a provider reading a profile of a profile file. An empty
+// profile is the one named by CRYPTOMKT_PROFILE, or DefaultProfile.
+// A missing file or profile is ErrNotFound.
+func File(path, profile string) Provider {
+	return Func(func(ctx context.Context) (Credentials, error) {
+		name := profile
+		if name == "" {
+			name = os.Getenv(EnvProfile)
+		}
+		if name == "" {
+			name = DefaultProfile
+		}
+		profiles, err := loadProfiles(path)
+		if err != nil {
+			return Credentials{}, err
+		}
+		credentials, ok := profiles[name]
+		if !ok {
+			return Credentials{}, fmt.Errorf("%w: no profile %q in %v", ErrNotFound, name, path)
+		}
+		if !credentials.valid() {
+			return Credentials{}, fmt.Errorf("CryptomarketSDKError: profile %q in %v without apiKey or apiSecret", name, path)
+		}
+		return credentials, nil
+	})
+}
+
+// DefaultPath returns the path of the profile file, the one named by
+// CRYPTOMKT_CREDENTIALS_FILE or, if unset, the first existing of
+// credentials.json, credentials.yaml and credentials.yml in the cryptomkt
+// directory of the user config directory, ~/.config/cryptomkt on linux.
+func DefaultPath() string {
+	if path := os.Getenv(EnvFile); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	candidates := []string{"credentials.json", "credentials.yaml", "credentials.yml"}
+	for _, name := range candidates {
+		path := filepath.Join(dir, "cryptomkt", name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return filepath.Join(dir, "cryptomkt", candidates[0])
+}
+
+// Chain returns a provider trying the providers in order, until one gives
+// credentials. Providers failing with ErrNotFound are skipped, other errors
+// stop the chain.
+func Chain(providers ...Provider) Provider {
+	return Func(func(ctx context.Context) (Credentials, error) {
+		reasons := make([]string, 0, len(providers))
+		for _, provider := range providers {
+			credentials, err := provider.Retrieve(ctx)
+			if err == nil {
+				return credentials, nil
+			}
+			if !errors.Is(err, ErrNotFound) {
+				return Credentials{}, err
+			}
+			reasons = append(reasons, strings.TrimPrefix(err.Error(), ErrNotFound.Error()+": "))
+		}
+		return Credentials{}, fmt.Errorf("%w: %v", ErrNotFound, strings.Join(reasons, "; "))
+	})
+}
+
+// Default returns the chain of the environment variables and the given
+// profile of the file at DefaultPath.
+func Default(profile string) Provider {
+	return Chain(Env(), Func(func(ctx context.Context) (Credentials, error) {
+		return File(DefaultPath(), profile).Retrieve(ctx)
+	}))
+}
@@ -0,0 +1,99 @@
+package credentials
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "credentials")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	yamlPath := writeFile(t, dir, "credentials.yaml", `
+# accounts of the desk
+profiles:
+  default:
+    apiKey: key # the main account
+    apiSecret: "se#cret"
+  bot:
+    apiKey: 'bot''s key'
+    apiSecret: bot-secret
+`)
+	jsonPath := writeFile(t, dir, "credentials.json", `{"profiles": {"bot": {"apiKey": "bot-key", "apiSecret": "bot-secret"}}}`)
+	flatPath := writeFile(t, dir, "keys.json", `{"apiKey": "key", "apiSecret": "secret"}`)
+
+	cases := []struct {
+		path, profile string
+		expected      Credentials
+	}{
+		{yamlPath, "", Credentials{"key", "se#cret"}},
+		{yamlPath, "bot", Credentials{"bot's key", "bot-secret"}},
+		{jsonPath, "bot", Credentials{"bot-key", "bot-secret"}},
+		{flatPath, "", Credentials{"key", "secret"}},
+	}
+	for _, c := range cases {
+		credentials, err := File(c.path, c.profile).Retrieve(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if credentials != c.expected {
+			t.Fatalf("wrong credentials of %v %v: %+v", c.path, c.profile, credentials)
+		}
+	}
+	if _, err = File(jsonPath, "").Retrieve(context.Background()); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected a missing profile, got %v", err)
+	}
+	if _, err = File(filepath.Join(dir, "missing.json"), "").Retrieve(context.Background()); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected a missing file, got %v", err)
+	}
+	badPath := writeFile(t, dir, "bad.yaml", "profiles:\n  default\n")
+	if _, err = File(badPath, "").Retrieve(context.Background()); err == nil || errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected an invalid file, got %v", err)
+	}
+}
+
+func TestChain(t *testing.T) {
+	os.Setenv("TEST_CRYPTOMKT_KEY", "")
+	os.Setenv("TEST_CRYPTOMKT_SECRET", "")
+	env := EnvVars("TEST_CRYPTOMKT_KEY", "TEST_CRYPTOMKT_SECRET")
+	calls := 0
+	custom := Func(func(ctx context.Context) (Credentials, error) {
+		calls++
+		return Credentials{"custom", "secret"}, nil
+	})
+	credentials, err := Chain(env, Static("", ""), custom).Retrieve(context.Background())
+	if err != nil || credentials.APIKey != "custom" {
+		t.Fatalf("expected the custom credentials, got %+v %v", credentials, err)
+	}
+	os.Setenv("TEST_CRYPTOMKT_KEY", "env")
+	os.Setenv("TEST_CRYPTOMKT_SECRET", "secret")
+	defer os.Unsetenv("TEST_CRYPTOMKT_KEY")
+	defer os.Unsetenv("TEST_CRYPTOMKT_SECRET")
+	credentials, err = Chain(env, custom).Retrieve(context.Background())
+	if err != nil || credentials.APIKey != "env" || calls != 1 {
+		t.Fatalf("expected the env credentials, got %+v %v", credentials, err)
+	}
+	failing := Func(func(ctx context.Context) (Credentials, error) {
+		return Credentials{}, errors.New("vault unreachable")
+	})
+	if _, err = Chain(failing, env).Retrieve(context.Background()); err == nil || errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected the chain to stop on a failure, got %v", err)
+	}
+	if _, err = Chain(Static("", "")).Retrieve(context.Background()); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected no credentials, got %v", err)
+	}
+}
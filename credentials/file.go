@@ -0,0 +1,143 @@
+package credentials
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type profileFile struct {
+	Credentials
+	Profiles map[string]Credentials `json:"profiles"`
+}
+
+// loadProfiles reads the profiles of a JSON or YAML file.
+func loadProfiles(path string) (map[string]Credentials, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: no file %v", ErrNotFound, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("CryptomarketSDKError: can't read the credentials file: %v", err)
+	}
+	var file profileFile
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = parseYAML(data, &file)
+	default:
+		err = json.Unmarshal(data, &file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("CryptomarketSDKError: invalid credentials file %v: %v", path, err)
+	}
+	profiles := file.Profiles
+	if profiles == nil {
+		profiles = make(map[string]Credentials)
+	}
+	if _, ok := profiles[DefaultProfile]; !ok && file.APIKey != "" {
+		profiles[DefaultProfile] = file.Credentials
+	}
+	return profiles, nil
+}
+
+// parseYAML parses the subset of YAML of the profile files: nested mappings
+// of scalars, with comments and quoted strings, into the file.
+func parseYAML(data []byte, file *profileFile) error {
+	type frame struct {
+		indent int
+		path   []string
+	}
+	stack := []frame{{indent: -1}}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(stripComment(line))
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		colon := strings.Index(trimmed, ":")
+		if colon <= 0 {
+			return fmt.Errorf("line %v: expected key: value", lineNumber)
+		}
+		key, err := unquote(strings.TrimSpace(trimmed[:colon]))
+		if err != nil {
+			return fmt.Errorf("line %v: %v", lineNumber, err)
+		}
+		value := strings.TrimSpace(trimmed[colon+1:])
+		for indent <= stack[len(stack)-1].indent {
+			stack = stack[:len(stack)-1]
+		}
+		path := append(append([]string(nil), stack[len(stack)-1].path...), key)
+		if value == "" {
+			stack = append(stack, frame{indent: indent, path: path})
+			continue
+		}
+		if value, err = unquote(value); err != nil {
+			return fmt.Errorf("line %v: %v", lineNumber, err)
+		}
+		if err = file.set(path, value); err != nil {
+			return fmt.Errorf("line %v: %v", lineNumber, err)
+		}
+	}
+	return scanner.Err()
+}
+
+func (file *profileFile) set(path []string, value string) error {
+	var credentials *Credentials
+	var field string
+	switch {
+	case len(path) == 1:
+		credentials, field = &file.Credentials, path[0]
+	case len(path) == 3 && path[0] == "profiles":
+		if file.Profiles == nil {
+			file.Profiles = make(map[string]Credentials)
+		}
+		profile := file.Profiles[path[1]]
+		defer func() { file.Profiles[path[1]] = profile }()
+		credentials, field = &profile, path[2]
+	default:
+		return fmt.Errorf("unexpected key %v", strings.Join(path, "."))
+	}
+	switch field {
+	case "apiKey":
+		credentials.APIKey = value
+	case "apiSecret":
+		credentials.APISecret = value
+	}
+	return nil
+}
+
+// stripComment removes a comment, a # at the start or after a space, outside of quotes.
+func stripComment(line string) string {
+	var quote rune
+	for idx, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#' && (idx == 0 || line[idx-1] == ' '):
+			return line[:idx]
+		}
+	}
+	return line
+}
+
+func unquote(value string) (string, error) {
+	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		return strings.ReplaceAll(value[1:len(value)-1], "''", "'"), nil
+	}
+	if strings.HasPrefix(value, `"`) {
+		return strconv.Unquote(value)
+	}
+	return value, nil
+}
@@ -0,0 +1,64 @@
+package downloader
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// checkpoint is the state of a download after its last completed window.
+type checkpoint struct {
+	Till time.Time `json:"till"` // data before till is written
+	Size int64     `json:"size"` // size of the file at till
+	// ids of the trades at till already written, as till may split trades of the same timestamp
+	IDs []int64 `json:"ids,omitempty"`
+}
+
+// checkpoints are the checkpoints of all the downloads of a directory, by task key.
+type checkpoints struct {
+	mutex  sync.Mutex
+	values map[string]checkpoint
+}
+
+func loadCheckpoints(path string) (*checkpoints, error) {
+	cps := &checkpoints{values: make(map[string]checkpoint)}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cps, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, &cps.values); err != nil {
+		return nil, err
+	}
+	return cps, nil
+}
+
+func (cps *checkpoints) get(key string) *checkpoint {
+	cps.mutex.Lock()
+	defer cps.mutex.Unlock()
+	if cp, ok := cps.values[key]; ok {
+		return &cp
+	}
+	return nil
+}
+
+// set records a checkpoint and saves all of them, replacing the file
+// atomically so a crash never leaves it half written.
+func (cps *checkpoints) set(path, key string, cp checkpoint) error {
+	cps.mutex.Lock()
+	defer cps.mutex.Unlock()
+	cps.values[key] = cp
+	data, err := json.MarshalIndent(cps.values, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err = ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
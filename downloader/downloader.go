@@ -0,0 +1,282 @@
+// Package downloader downloads historical candles and trades of many symbols
+// from the exchange into files, one per symbol.
+//
+// Downloads are split into windows under the limit of rows per request, run
+// in parallel across symbols under a shared rate limit, and are checkpointed
+// after every window so an interrupted download resumes where it stopped.
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/cryptomarket/cryptomarket-go/args"
+	"github.com/cryptomarket/cryptomarket-go/candles"
+	"github.com/cryptomarket/cryptomarket-go/metrics"
+	"github.com/cryptomarket/cryptomarket-go/models"
+)
+
+// Source is a source of historical market data, like the rest client.
+type Source interface {
+	GetCandlesOfSymbol(ctx context.Context, arguments ...args.Argument) ([]models.Candle, error)
+	GetTradesOfSymbol(ctx context.Context, arguments ...args.Argument) ([]models.PublicTrade, error)
+}
+
+// Format is the format of the downloaded files
+type Format string
+
+// file formats
+const (
+	FormatCSV   Format = "csv"
+	FormatJSONL Format = "jsonl"
+)
+
+// Gap is a range of time without candles after refetching.
+// Periods without trades are legitimate gaps.
+type Gap struct {
+	From time.Time
+	Till time.Time
+}
+
+// Result is the outcome of the download of a symbol.
+type Result struct {
+	Symbol string
+	Path   string
+	Rows   int64 // rows written by this run, excluding resumed ones
+	Gaps   []Gap // candle gaps remaining after refetching
+	Err    error
+}
+
+// Downloader downloads historical market data into a directory.
+type Downloader struct {
+	source         Source
+	dir            string
+	format         Format
+	workers        int
+	limit          int
+	retries        int
+	retryDelay     time.Duration
+	limiter        *limiter
+	checkpoints    *checkpoints
+	checkpointPath string
+	metrics        metrics.Recorder
+}
+
+// Option configures a Downloader
+type Option func(*Downloader)
+
+// WithFormat sets the format of the files. Default is FormatCSV.
+func WithFormat(format Format) Option {
+	return func(downloader *Downloader) {
+		downloader.format = format
+	}
+}
+
+// WithWorkers sets the number of symbols downloaded in parallel. Default is 4.
+func WithWorkers(workers int) Option {
+	return func(downloader *Downloader) {
+		downloader.workers = workers
+	}
+}
+
+// WithRateLimit sets the maximum number of requests per second, shared by all
+// the workers. Default is 10.
+func WithRateLimit(requestsPerSecond int) Option {
+	return func(downloader *Downloader) {
+		downloader.limiter = newLimiter(requestsPerSecond)
+	}
+}
+
+// WithLimit sets the number of rows per request. Default and maximum is 1000.
+func WithLimit(limit int) Option {
+	return func(downloader *Downloader) {
+		downloader.limit = limit
+	}
+}
+
+// WithRetries sets the number of retries of a failed request, and the delay
+// before the first retry, doubled on each following one. Default is 3 retries after 1 second.
+func WithRetries(retries int, delay time.Duration) Option {
+	return func(downloader *Downloader) {
+		downloader.retries = retries
+		downloader.retryDelay = delay
+	}
+}
+
+// WithMetrics records the retries and the rate limit waits of the downloader.
+func WithMetrics(recorder metrics.Recorder) Option {
+	return func(downloader *Downloader) {
+		downloader.metrics = recorder
+	}
+}
+
+// New returns a Downloader writing to dir. The checkpoints of the downloads
+// are kept in dir, in the checkpoints.json file.
+func New(source Source, dir string, options ...Option) *Downloader {
+	downloader := &Downloader{
+		source:         source,
+		dir:            dir,
+		format:         FormatCSV,
+		workers:        4,
+		limit:          1000,
+		retries:        3,
+		retryDelay:     time.Second,
+		limiter:        newLimiter(10),
+		checkpointPath: filepath.Join(dir, "checkpoints.json"),
+		metrics:        metrics.Discard,
+	}
+	for _, option := range options {
+		option(downloader)
+	}
+	return downloader
+}
+
+// task is the download of the data of a symbol in a range of time
+type task struct {
+	kind   string
+	symbol string
+	period args.PeriodType
+	from   time.Time
+	till   time.Time
+}
+
+// key is the key of the checkpoint of a task. It includes the format and the
+// path of the file, as the size of a checkpoint is only valid for that file.
+func (downloader *Downloader) key(t task) string {
+	path := t.path(downloader.dir, downloader.format)
+	return t.kind + ":" + t.symbol + ":" + string(t.period) + ":" + string(downloader.format) + ":" + path
+}
+
+func (t task) path(dir string, format Format) string {
+	name := t.kind + "_" + t.symbol
+	if t.period != "" {
+		name += "_" + string(t.period)
+	}
+	return filepath.Join(dir, name+"."+string(format))
+}
+
+// DownloadCandles downloads the candles of the symbols at the given period,
+// from (included) till (excluded). Returns a result per symbol, in order.
+func (downloader *Downloader) DownloadCandles(ctx context.Context, symbols []string, period args.PeriodType, from, till time.Time) ([]Result, error) {
+	if _, err := candles.PeriodDuration(period); err != nil {
+		return nil, err
+	}
+	tasks := make([]task, len(symbols))
+	for idx, symbol := range symbols {
+		tasks[idx] = task{kind: kindCandles, symbol: symbol, period: period, from: from, till: till}
+	}
+	return downloader.run(ctx, tasks)
+}
+
+// DownloadTrades downloads the trades of the symbols from (included) till
+// (excluded). Returns a result per symbol, in order.
+func (downloader *Downloader) DownloadTrades(ctx context.Context, symbols []string, from, till time.Time) ([]Result, error) {
+	tasks := make([]task, len(symbols))
+	for idx, symbol := range symbols {
+		tasks[idx] = task{kind: kindTrades, symbol: symbol, from: from, till: till}
+	}
+	return downloader.run(ctx, tasks)
+}
+
+func (downloader *Downloader) run(ctx context.Context, tasks []task) ([]Result, error) {
+	if downloader.format != FormatCSV && downloader.format != FormatJSONL {
+		return nil, fmt.Errorf("CryptomarketSDKError: invalid format: %q", downloader.format)
+	}
+	if downloader.limit <= 0 || downloader.limit > 1000 {
+		downloader.limit = 1000
+	}
+	cps, err := loadCheckpoints(downloader.checkpointPath)
+	if err != nil {
+		return nil, err
+	}
+	downloader.checkpoints = cps
+
+	results := make([]Result, len(tasks))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	workers := downloader.workers
+	if workers < 1 {
+		workers = 1
+	}
+	for worker := 0; worker < workers; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range indexes {
+				results[idx] = downloader.download(ctx, tasks[idx])
+			}
+		}()
+	}
+	for idx := range tasks {
+		indexes <- idx
+	}
+	close(indexes)
+	wg.Wait()
+	return results, nil
+}
+
+func (downloader *Downloader) download(ctx context.Context, t task) Result {
+	result := Result{Symbol: t.symbol, Path: t.path(downloader.dir, downloader.format)}
+	cp, err := resumable(result.Path, downloader.checkpoints.get(downloader.key(t)))
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	out, err := openOutput(result.Path, downloader.format, t.kind, cp)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer out.close()
+	start := t.from
+	var ids []int64
+	if cp != nil && !cp.Till.Before(start) {
+		start, ids = cp.Till, cp.IDs
+	}
+	if t.kind == kindCandles {
+		err = downloader.downloadCandles(ctx, t, start, out, &result)
+	} else {
+		err = downloader.downloadTrades(ctx, t, start, ids, out, &result)
+	}
+	result.Err = err
+	return result
+}
+
+// commit flushes the written rows and checkpoints the download up to till,
+// with the ids of the trades at till already written.
+func (downloader *Downloader) commit(t task, out *output, till time.Time, ids []int64) error {
+	size, err := out.flush()
+	if err != nil {
+		return err
+	}
+	return downloader.checkpoints.set(downloader.checkpointPath, downloader.key(t), checkpoint{Till: till, Size: size, IDs: ids})
+}
+
+// retry calls fn under the rate limit, retrying it on failure.
+func (downloader *Downloader) retry(ctx context.Context, kind string, fn func() error) error {
+	delay := downloader.retryDelay
+	var err error
+	for attempt := 0; attempt <= downloader.retries; attempt++ {
+		if attempt > 0 {
+			downloader.metrics.Add(metrics.DownloadRetries, metrics.Labels{"kind": kind}, 1)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+			delay *= 2
+		}
+		start := time.Now()
+		if err = downloader.limiter.wait(ctx); err != nil {
+			return err
+		}
+		downloader.metrics.Observe(metrics.DownloadLimiterWait, nil, time.Since(start).Seconds())
+		if err = fn(); err == nil {
+			return nil
+		}
+	}
+	return err
+}
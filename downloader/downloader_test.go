@@ -0,0 +1,298 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cryptomarket/cryptomarket-go/args"
+	"github.com/cryptomarket/cryptomarket-go/candles"
+	"github.com/cryptomarket/cryptomarket-go/models"
+)
+
+var baseTime = time.Date(2021, 1, 20, 20, 0, 0, 0, time.UTC)
+
+// fakeSource serves minute candles and trades of a symbol, with missing
+// candles, inclusive tills and failures like the exchange.
+type fakeSource struct {
+	mutex     sync.Mutex
+	candles   []models.Candle
+	trades    []models.PublicTrade
+	late      map[string]bool // candles missing from the first request of their window
+	failAfter int             // fail every request after this many, if positive
+	requests  int
+}
+
+func (source *fakeSource) params(arguments []args.Argument) (map[string]interface{}, error) {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+	source.requests++
+	if source.failAfter > 0 && source.requests > source.failAfter {
+		return nil, errors.New("connection reset")
+	}
+	params := make(map[string]interface{})
+	for _, argument := range arguments {
+		argument(params)
+	}
+	return params, nil
+}
+
+func inRange(timestamp string, params map[string]interface{}) bool {
+	ts, _ := time.Parse(time.RFC3339, timestamp)
+	from, _ := time.Parse(time.RFC3339, params["from"].(string))
+	till, _ := time.Parse(time.RFC3339, params["till"].(string))
+	return !ts.Before(from) && !ts.After(till)
+}
+
+func (source *fakeSource) GetCandlesOfSymbol(ctx context.Context, arguments ...args.Argument) ([]models.Candle, error) {
+	params, err := source.params(arguments)
+	if err != nil {
+		return nil, err
+	}
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+	result := make([]models.Candle, 0)
+	for _, candle := range source.candles {
+		if len(result) == params["limit"].(int) {
+			break
+		}
+		if !inRange(candle.Timestamp, params) {
+			continue
+		}
+		if source.late[candle.Timestamp] {
+			delete(source.late, candle.Timestamp)
+			continue
+		}
+		result = append(result, candle)
+	}
+	return result, nil
+}
+
+func (source *fakeSource) GetTradesOfSymbol(ctx context.Context, arguments ...args.Argument) ([]models.PublicTrade, error) {
+	params, err := source.params(arguments)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]models.PublicTrade, 0)
+	skipped := 0
+	for _, trade := range source.trades {
+		if len(result) == params["limit"].(int) {
+			break
+		}
+		if !inRange(trade.Timestamp, params) {
+			continue
+		}
+		if skipped < params["offset"].(int) {
+			skipped++
+			continue
+		}
+		result = append(result, trade)
+	}
+	return result, nil
+}
+
+func minuteCandles(minutes int, missing ...int) []models.Candle {
+	skip := make(map[int]bool)
+	for _, minute := range missing {
+		skip[minute] = true
+	}
+	result := make([]models.Candle, 0)
+	for minute := 0; minute < minutes; minute++ {
+		if skip[minute] {
+			continue
+		}
+		result = append(result, models.Candle{
+			Timestamp: baseTime.Add(time.Duration(minute) * time.Minute).Format(candles.TimestampFormat),
+			Open:      "1", Close: "1", Min: "1", Max: "1", Volume: "1", VolumeQuote: "1",
+		})
+	}
+	return result
+}
+
+func readLines(t *testing.T, path string) []string {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestDownloadCandles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "downloader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	source := &fakeSource{
+		candles: minuteCandles(25, 7),
+		late:    map[string]bool{baseTime.Add(12 * time.Minute).Format(candles.TimestampFormat): true},
+	}
+	downloader := New(source, dir, WithLimit(10), WithRateLimit(0))
+	results, err := downloader.DownloadCandles(context.Background(), []string{"EOSETH", "ETHBTC"}, args.PeriodType1Minutes, baseTime, baseTime.Add(25*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, result := range results {
+		if result.Err != nil {
+			t.Fatal(result.Err)
+		}
+	}
+	result := results[0]
+	if result.Symbol != "EOSETH" || result.Path != filepath.Join(dir, "candles_EOSETH_M1.csv") {
+		t.Fatalf("wrong result: %+v", result)
+	}
+	// the late candle is refetched, the missing one is reported
+	if result.Rows != 24 {
+		t.Fatalf("expected 24 rows, got %v", result.Rows)
+	}
+	if len(result.Gaps) != 1 || !result.Gaps[0].From.Equal(baseTime.Add(7*time.Minute)) || !result.Gaps[0].Till.Equal(baseTime.Add(8*time.Minute)) {
+		t.Fatalf("wrong gaps: %+v", result.Gaps)
+	}
+	lines := readLines(t, result.Path)
+	if len(lines) != 25 || lines[0] != strings.Join(candleHeader, ",") {
+		t.Fatalf("wrong file: %v", lines)
+	}
+	// a finished download is not downloaded again
+	requests := source.requests
+	results, err = downloader.DownloadCandles(context.Background(), []string{"EOSETH"}, args.PeriodType1Minutes, baseTime, baseTime.Add(25*time.Minute))
+	if err != nil || results[0].Rows != 0 || source.requests != requests {
+		t.Fatalf("downloaded again: %+v %v", results, err)
+	}
+}
+
+func TestDownloadResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "downloader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	source := &fakeSource{candles: minuteCandles(35), failAfter: 2}
+	downloader := New(source, dir, WithLimit(10), WithRateLimit(0), WithRetries(1, time.Millisecond), WithFormat(FormatJSONL))
+	from, till := baseTime, baseTime.Add(35*time.Minute)
+	results, err := downloader.DownloadCandles(context.Background(), []string{"EOSETH"}, args.PeriodType1Minutes, from, till)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Err == nil || results[0].Rows != 20 {
+		t.Fatalf("expected a failure after 2 windows: %+v", results[0])
+	}
+	source.failAfter = 0
+	results, err = downloader.DownloadCandles(context.Background(), []string{"EOSETH"}, args.PeriodType1Minutes, from, till)
+	if err != nil || results[0].Err != nil {
+		t.Fatal(err, results[0].Err)
+	}
+	if results[0].Rows != 15 {
+		t.Fatalf("expected to resume with 15 rows, got %v", results[0].Rows)
+	}
+	lines := readLines(t, results[0].Path)
+	if len(lines) != 35 {
+		t.Fatalf("expected 35 lines, got %v", len(lines))
+	}
+	for idx, line := range lines {
+		if !strings.Contains(line, baseTime.Add(time.Duration(idx)*time.Minute).Format(candles.TimestampFormat)) {
+			t.Fatalf("wrong line %v: %v", idx, line)
+		}
+	}
+}
+
+func TestDownloadRestartsWithoutFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "downloader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	source := &fakeSource{candles: minuteCandles(35), failAfter: 2}
+	downloader := New(source, dir, WithLimit(10), WithRateLimit(0), WithRetries(0, time.Millisecond))
+	from, till := baseTime, baseTime.Add(35*time.Minute)
+	results, err := downloader.DownloadCandles(context.Background(), []string{"EOSETH"}, args.PeriodType1Minutes, from, till)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Err == nil {
+		t.Fatal("expected a failure after 2 windows")
+	}
+	// the file shrinks behind the checkpoint
+	if err = os.Truncate(results[0].Path, 10); err != nil {
+		t.Fatal(err)
+	}
+	source.failAfter = 0
+	results, err = downloader.DownloadCandles(context.Background(), []string{"EOSETH"}, args.PeriodType1Minutes, from, till)
+	if err != nil || results[0].Err != nil {
+		t.Fatal(err, results[0].Err)
+	}
+	if results[0].Rows != 35 {
+		t.Fatalf("expected to start over with 35 rows, got %v", results[0].Rows)
+	}
+	if lines := readLines(t, results[0].Path); len(lines) != 36 || lines[0] != strings.Join(candleHeader, ",") {
+		t.Fatalf("expected a header and 35 lines, got %v", lines)
+	}
+	// another format is another file, with its own checkpoint
+	downloader = New(source, dir, WithLimit(10), WithRateLimit(0), WithFormat(FormatJSONL))
+	results, err = downloader.DownloadCandles(context.Background(), []string{"EOSETH"}, args.PeriodType1Minutes, from, till)
+	if err != nil || results[0].Err != nil {
+		t.Fatal(err, results[0].Err)
+	}
+	if results[0].Rows != 35 {
+		t.Fatalf("expected 35 rows in the jsonl file, got %v", results[0].Rows)
+	}
+}
+
+func TestDownloadTrades(t *testing.T) {
+	dir, err := ioutil.TempDir("", "downloader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	source := &fakeSource{}
+	// trades sharing timestamps across page boundaries, and a page of a single timestamp
+	seconds := []int{0, 0, 1, 1, 1, 2, 3, 3, 3, 3, 3, 3, 3, 4, 5}
+	for idx, second := range seconds {
+		source.trades = append(source.trades, models.PublicTrade{
+			ID:        int64(idx + 1),
+			Price:     "1",
+			Quantity:  "1",
+			Side:      "buy",
+			Timestamp: baseTime.Add(time.Duration(second) * time.Second).Format(candles.TimestampFormat),
+		})
+	}
+	downloader := New(source, dir, WithLimit(4), WithRateLimit(0))
+	results, err := downloader.DownloadTrades(context.Background(), []string{"EOSETH"}, baseTime, baseTime.Add(5*time.Second))
+	if err != nil || results[0].Err != nil {
+		t.Fatal(err, results[0].Err)
+	}
+	lines := readLines(t, results[0].Path)
+	// the trade at the till is excluded
+	if results[0].Rows != 14 || len(lines) != 15 {
+		t.Fatalf("expected 14 trades, got %v: %v", results[0].Rows, lines)
+	}
+	for idx, line := range lines[1:] {
+		if id := strings.Split(line, ",")[0]; id != strconv.Itoa(idx+1) {
+			t.Fatalf("wrong trade at %v: %v", idx, line)
+		}
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	limiter := newLimiter(100)
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if err := limiter.wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Fatalf("not limited: %v", elapsed)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := newLimiter(1).wait(ctx); err == nil {
+		t.Fatal("expected a canceled wait")
+	}
+}
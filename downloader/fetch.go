@@ -0,0 +1,178 @@
+package downloader
+
+import (
+	"context"
+	"time"
+
+	"github.com/cryptomarket/cryptomarket-go/args"
+	"github.com/cryptomarket/cryptomarket-go/candles"
+	"github.com/cryptomarket/cryptomarket-go/models"
+)
+
+const (
+	kindCandles = "candles"
+	kindTrades  = "trades"
+)
+
+// downloadCandles downloads the candles in windows of limit candles.
+func (downloader *Downloader) downloadCandles(ctx context.Context, t task, start time.Time, out *output, result *Result) error {
+	period, err := candles.PeriodDuration(t.period)
+	if err != nil {
+		return err
+	}
+	window := period * time.Duration(downloader.limit)
+	for from := start; from.Before(t.till); from = from.Add(window) {
+		till := from.Add(window)
+		if till.After(t.till) {
+			till = t.till
+		}
+		rows, err := downloader.fetchCandles(ctx, t, from, till)
+		if err != nil {
+			return err
+		}
+		gaps := findGaps(rows, from, till, period)
+		if len(gaps) > 0 {
+			// refetch once, the exchange may have been catching up
+			if rows, err = downloader.fetchCandles(ctx, t, from, till); err != nil {
+				return err
+			}
+			gaps = findGaps(rows, from, till, period)
+			result.Gaps = append(result.Gaps, gaps...)
+		}
+		for _, candle := range rows {
+			if err = out.writeCandle(candle); err != nil {
+				return err
+			}
+			result.Rows++
+		}
+		if err = downloader.commit(t, out, till, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (downloader *Downloader) fetchCandles(ctx context.Context, t task, from, till time.Time) (rows []models.Candle, err error) {
+	err = downloader.retry(ctx, t.kind, func() error {
+		rows, err = downloader.source.GetCandlesOfSymbol(
+			ctx,
+			args.Symbol(t.symbol),
+			args.Period(t.period),
+			args.Sort(args.SortTypeASC),
+			args.From(from.UTC().Format(candles.TimestampFormat)),
+			args.Till(till.UTC().Format(candles.TimestampFormat)),
+			args.Limit(downloader.limit),
+		)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	// the till of the exchange is inclusive
+	inRange := rows[:0]
+	for _, candle := range rows {
+		if ts, err := time.Parse(time.RFC3339, candle.Timestamp); err == nil && !ts.Before(from) && ts.Before(till) {
+			inRange = append(inRange, candle)
+		}
+	}
+	return inRange, nil
+}
+
+// findGaps returns the ranges of missing candles in a window, the candles being sorted.
+func findGaps(candles []models.Candle, from, till time.Time, period time.Duration) []Gap {
+	gaps := make([]Gap, 0)
+	expected := from
+	for _, candle := range candles {
+		ts, err := time.Parse(time.RFC3339, candle.Timestamp)
+		if err != nil {
+			continue
+		}
+		if ts.After(expected) {
+			gaps = append(gaps, Gap{From: expected, Till: ts})
+		}
+		expected = ts.Add(period)
+	}
+	if expected.Before(till) {
+		gaps = append(gaps, Gap{From: expected, Till: till})
+	}
+	return gaps
+}
+
+// downloadTrades downloads the trades in pages of limit trades, using the
+// timestamp of the last trade of a page as the start of the next one.
+// Trades sharing the boundary timestamp are deduplicated by id, ids being the
+// trades at start already written by a previous run.
+func (downloader *Downloader) downloadTrades(ctx context.Context, t task, start time.Time, ids []int64, out *output, result *Result) error {
+	cursor := start
+	offset := 0
+	seen := make(map[int64]bool)
+	for _, id := range ids {
+		seen[id] = true
+	}
+	for cursor.Before(t.till) {
+		trades, err := downloader.fetchTrades(ctx, t, cursor, offset)
+		if err != nil {
+			return err
+		}
+		last := cursor
+		boundary := make(map[int64]bool)
+		for _, trade := range trades {
+			ts, err := time.Parse(time.RFC3339, trade.Timestamp)
+			if err != nil || ts.Before(cursor) || !ts.Before(t.till) {
+				continue
+			}
+			if ts.After(last) {
+				last = ts
+				boundary = make(map[int64]bool)
+			}
+			boundary[trade.ID] = true
+			if seen[trade.ID] {
+				continue
+			}
+			seen[trade.ID] = true
+			if err = out.writeTrade(trade); err != nil {
+				return err
+			}
+			result.Rows++
+		}
+		if len(trades) < downloader.limit {
+			// the window is exhausted
+			return downloader.commit(t, out, t.till, nil)
+		}
+		if last.Equal(cursor) {
+			// a full page on a single timestamp, page through it by offset
+			offset += len(trades)
+			for id := range boundary {
+				seen[id] = true
+			}
+		} else {
+			cursor, offset = last, 0
+			seen = boundary
+		}
+		ids := make([]int64, 0, len(seen))
+		for id := range seen {
+			ids = append(ids, id)
+		}
+		if err = downloader.commit(t, out, cursor, ids); err != nil {
+			return err
+		}
+	}
+	return downloader.commit(t, out, t.till, nil)
+}
+
+func (downloader *Downloader) fetchTrades(ctx context.Context, t task, from time.Time, offset int) (trades []models.PublicTrade, err error) {
+	err = downloader.retry(ctx, t.kind, func() error {
+		trades, err = downloader.source.GetTradesOfSymbol(
+			ctx,
+			args.Symbol(t.symbol),
+			args.Sort(args.SortTypeASC),
+			args.SortBy(args.SortByTypeTimestamp),
+			args.From(from.UTC().Format(candles.TimestampFormat)),
+			args.Till(t.till.UTC().Format(candles.TimestampFormat)),
+			args.Limit(downloader.limit),
+			args.Offset(offset),
+		)
+		return err
+	})
+	return trades, err
+}
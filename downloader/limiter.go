@@ -0,0 +1,46 @@
+package downloader
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// limiter spaces requests evenly to stay under a number of requests per second.
+type limiter struct {
+	mutex    sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newLimiter(requestsPerSecond int) *limiter {
+	if requestsPerSecond <= 0 {
+		return &limiter{}
+	}
+	return &limiter{interval: time.Second / time.Duration(requestsPerSecond)}
+}
+
+// wait blocks until a request can be made, or the context is done.
+func (l *limiter) wait(ctx context.Context) error {
+	l.mutex.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mutex.Unlock()
+
+	delay := time.Until(at)
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,123 @@
+package downloader
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"strconv"
+
+	"github.com/cryptomarket/cryptomarket-go/models"
+)
+
+var (
+	candleHeader = []string{"timestamp", "open", "close", "min", "max", "volume", "volumeQuote"}
+	tradeHeader  = []string{"id", "timestamp", "price", "quantity", "side"}
+)
+
+// output is a file of rows of a download.
+type output struct {
+	file   *os.File
+	buffer *bufio.Writer
+	csv    *csv.Writer
+	format Format
+}
+
+// resumable returns the checkpoint if the download can resume from it, or nil
+// if the file is missing or smaller than at the checkpoint, and the download
+// must start over.
+func resumable(path string, cp *checkpoint) (*checkpoint, error) {
+	if cp == nil {
+		return nil, nil
+	}
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() < cp.Size {
+		return nil, nil
+	}
+	return cp, nil
+}
+
+// openOutput opens the file of a download. If resumed from a checkpoint the
+// file is truncated to its size at the checkpoint, dropping the rows of an
+// unfinished window, otherwise the file is truncated and a header written.
+func openOutput(path string, format Format, kind string, cp *checkpoint) (*output, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	var size int64
+	if cp != nil {
+		size = cp.Size
+	}
+	if err = file.Truncate(size); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if _, err = file.Seek(size, 0); err != nil {
+		file.Close()
+		return nil, err
+	}
+	buffer := bufio.NewWriter(file)
+	out := &output{file: file, buffer: buffer, csv: csv.NewWriter(buffer), format: format}
+	if size == 0 && format == FormatCSV {
+		header := candleHeader
+		if kind == kindTrades {
+			header = tradeHeader
+		}
+		if err = out.csv.Write(header); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func (out *output) writeCandle(candle models.Candle) error {
+	if out.format == FormatJSONL {
+		return out.writeJSON(candle)
+	}
+	return out.csv.Write([]string{candle.Timestamp, candle.Open, candle.Close, candle.Min, candle.Max, candle.Volume, candle.VolumeQuote})
+}
+
+func (out *output) writeTrade(trade models.PublicTrade) error {
+	if out.format == FormatJSONL {
+		return out.writeJSON(trade)
+	}
+	return out.csv.Write([]string{strconv.FormatInt(trade.ID, 10), trade.Timestamp, trade.Price, trade.Quantity, string(trade.Side)})
+}
+
+func (out *output) writeJSON(value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if _, err = out.buffer.Write(data); err != nil {
+		return err
+	}
+	return out.buffer.WriteByte('\n')
+}
+
+// flush writes the buffered rows to disk and returns the size of the file.
+func (out *output) flush() (int64, error) {
+	out.csv.Flush()
+	if err := out.csv.Error(); err != nil {
+		return 0, err
+	}
+	if err := out.buffer.Flush(); err != nil {
+		return 0, err
+	}
+	if err := out.file.Sync(); err != nil {
+		return 0, err
+	}
+	return out.file.Seek(0, 1)
+}
+
+func (out *output) close() error {
+	return out.file.Close()
+}
@@ -0,0 +1,457 @@
+// Package indicators computes technical indicators over candles, both over
+// candle slices and incrementally over live candle feeds.
+//
+// Indicators use float64 math. Prices and volumes are parsed from the decimal
+// strings of the candles, so values carry the usual float64 rounding, about 15
+// significant digits, which is enough for signals but not for accounting.
+// Unparseable values are taken as NaN and propagate to the results.
+//
+// Incremental indicators take candles oldest first with Update. A candle with
+// the same timestamp as the previous one replaces it instead of adding a new
+// point, so the open candle of a live feed can be updated on every change
+// without recomputing the history.
+package indicators
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/cryptomarket/cryptomarket-go/models"
+)
+
+func parse(value string) float64 {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return math.NaN()
+	}
+	return f
+}
+
+// step tracks the timestamp of the last candle of an indicator, to know if a
+// new candle replaces it.
+type step struct {
+	timestamp string
+	started   bool
+}
+
+// replaces reports if the candle replaces the last one, and records it as the last one.
+func (s *step) replaces(candle models.Candle) bool {
+	replaces := s.started && s.timestamp == candle.Timestamp
+	s.timestamp = candle.Timestamp
+	s.started = true
+	return replaces
+}
+
+func checkPeriod(period int) error {
+	if period <= 0 {
+		return fmt.Errorf("CryptomarketSDKError: invalid indicator period: %v", period)
+	}
+	return nil
+}
+
+// window is a fixed size window of the last values, with their sum. The sum is
+// recomputed from the values every size additions, so the rounding errors of
+// the running sum don't accumulate, and a NaN no longer in the window is gone.
+type window struct {
+	size   int
+	values []float64
+	sum    float64
+	added  int
+}
+
+func (w window) clone() window {
+	w.values = append([]float64(nil), w.values...)
+	return w
+}
+
+func (w *window) add(value float64) {
+	w.values = append(w.values, value)
+	w.sum += value
+	if len(w.values) > w.size {
+		w.sum -= w.values[0]
+		w.values = w.values[1:]
+	}
+	w.added++
+	if w.added%w.size == 0 {
+		w.sum = 0
+		for _, value := range w.values {
+			w.sum += value
+		}
+	}
+}
+
+func (w *window) full() bool {
+	return len(w.values) == w.size
+}
+
+func (w *window) mean() float64 {
+	return w.sum / float64(len(w.values))
+}
+
+// ema is an exponential moving average seeded with the simple average of its first values.
+type ema struct {
+	period int
+	count  int
+	sum    float64
+	value  float64
+}
+
+func (e *ema) add(value float64) {
+	e.count++
+	if e.count < e.period {
+		e.sum += value
+		return
+	}
+	if e.count == e.period {
+		e.value = (e.sum + value) / float64(e.period)
+		return
+	}
+	k := 2 / float64(e.period+1)
+	e.value = value*k + e.value*(1-k)
+}
+
+func (e *ema) ready() bool {
+	return e.count >= e.period
+}
+
+// SMA is the simple moving average of the close prices.
+type SMA struct {
+	step
+	cur, prev window
+}
+
+// NewSMA returns a simple moving average of the given period. Fails if
+// period is not positive.
+func NewSMA(period int) (*SMA, error) {
+	if err := checkPeriod(period); err != nil {
+		return nil, err
+	}
+	return &SMA{cur: window{size: period}}, nil
+}
+
+// Update adds a candle and returns the average, and if it is ready.
+func (sma *SMA) Update(candle models.Candle) (float64, bool) {
+	if sma.replaces(candle) {
+		sma.cur = sma.prev.clone()
+	} else {
+		sma.prev = sma.cur.clone()
+	}
+	sma.cur.add(parse(candle.Close))
+	return sma.Value()
+}
+
+// Value returns the current average, and if it is ready.
+func (sma *SMA) Value() (float64, bool) {
+	if !sma.cur.full() {
+		return 0, false
+	}
+	return sma.cur.mean(), true
+}
+
+// EMA is the exponential moving average of the close prices, seeded with
+// the simple average of its first period candles.
+type EMA struct {
+	step
+	cur, prev ema
+}
+
+// NewEMA returns an exponential moving average of the given period. Fails if
+// period is not positive.
+func NewEMA(period int) (*EMA, error) {
+	if err := checkPeriod(period); err != nil {
+		return nil, err
+	}
+	return &EMA{cur: ema{period: period}}, nil
+}
+
+// Update adds a candle and returns the average, and if it is ready.
+func (e *EMA) Update(candle models.Candle) (float64, bool) {
+	if e.replaces(candle) {
+		e.cur = e.prev
+	} else {
+		e.prev = e.cur
+	}
+	e.cur.add(parse(candle.Close))
+	return e.Value()
+}
+
+// Value returns the current average, and if it is ready.
+func (e *EMA) Value() (float64, bool) {
+	return e.cur.value, e.cur.ready()
+}
+
+type rsiState struct {
+	prevClose float64
+	count     int
+	avgGain   float64
+	avgLoss   float64
+}
+
+// RSI is the relative strength index of the close prices, using Wilder's smoothing.
+type RSI struct {
+	step
+	period    int
+	cur, prev rsiState
+}
+
+// NewRSI returns a relative strength index of the given period. Fails if
+// period is not positive.
+func NewRSI(period int) (*RSI, error) {
+	if err := checkPeriod(period); err != nil {
+		return nil, err
+	}
+	return &RSI{period: period}, nil
+}
+
+// Update adds a candle and returns the index, between 0 and 100, and if it is ready.
+func (rsi *RSI) Update(candle models.Candle) (float64, bool) {
+	if rsi.replaces(candle) {
+		rsi.cur = rsi.prev
+	} else {
+		rsi.prev = rsi.cur
+	}
+	closePrice := parse(candle.Close)
+	state := &rsi.cur
+	if state.count > 0 {
+		change := closePrice - state.prevClose
+		gain, loss := math.Max(change, 0), math.Max(-change, 0)
+		n := float64(rsi.period)
+		if state.count <= rsi.period {
+			state.avgGain += gain / n
+			state.avgLoss += loss / n
+		} else {
+			state.avgGain = (state.avgGain*(n-1) + gain) / n
+			state.avgLoss = (state.avgLoss*(n-1) + loss) / n
+		}
+	}
+	state.prevClose = closePrice
+	state.count++
+	return rsi.Value()
+}
+
+// Value returns the current index, and if it is ready.
+func (rsi *RSI) Value() (float64, bool) {
+	if rsi.cur.count <= rsi.period {
+		return 0, false
+	}
+	if rsi.cur.avgLoss == 0 {
+		return 100, true
+	}
+	rs := rsi.cur.avgGain / rsi.cur.avgLoss
+	return 100 - 100/(1+rs), true
+}
+
+// MACDValue is a value of the MACD indicator.
+type MACDValue struct {
+	MACD      float64 // fast EMA minus slow EMA
+	Signal    float64 // EMA of the MACD
+	Histogram float64 // MACD minus signal
+}
+
+type macdState struct {
+	fast, slow, signal ema
+}
+
+// MACD is the moving average convergence divergence of the close prices.
+type MACD struct {
+	step
+	cur, prev macdState
+}
+
+// NewMACD returns a MACD with the given periods, usually 12, 26 and 9.
+// Fails if a period is not positive.
+func NewMACD(fast, slow, signal int) (*MACD, error) {
+	for _, period := range []int{fast, slow, signal} {
+		if err := checkPeriod(period); err != nil {
+			return nil, err
+		}
+	}
+	return &MACD{cur: macdState{
+		fast:   ema{period: fast},
+		slow:   ema{period: slow},
+		signal: ema{period: signal},
+	}}, nil
+}
+
+// Update adds a candle and returns the MACD, and if it is ready.
+func (macd *MACD) Update(candle models.Candle) (MACDValue, bool) {
+	if macd.replaces(candle) {
+		macd.cur = macd.prev
+	} else {
+		macd.prev = macd.cur
+	}
+	closePrice := parse(candle.Close)
+	macd.cur.fast.add(closePrice)
+	macd.cur.slow.add(closePrice)
+	if macd.cur.fast.ready() && macd.cur.slow.ready() {
+		macd.cur.signal.add(macd.cur.fast.value - macd.cur.slow.value)
+	}
+	return macd.Value()
+}
+
+// Value returns the current MACD, and if it is ready.
+func (macd *MACD) Value() (MACDValue, bool) {
+	if !macd.cur.signal.ready() {
+		return MACDValue{}, false
+	}
+	value := macd.cur.fast.value - macd.cur.slow.value
+	return MACDValue{
+		MACD:      value,
+		Signal:    macd.cur.signal.value,
+		Histogram: value - macd.cur.signal.value,
+	}, true
+}
+
+// BollingerValue is a value of the Bollinger bands indicator.
+type BollingerValue struct {
+	Middle float64 // simple moving average
+	Upper  float64 // middle plus k standard deviations
+	Lower  float64 // middle minus k standard deviations
+}
+
+// Bollinger are the Bollinger bands of the close prices, using the population
+// standard deviation.
+type Bollinger struct {
+	step
+	k         float64
+	cur, prev window
+}
+
+// NewBollinger returns Bollinger bands of the given period and width in
+// standard deviations, usually 20 and 2. Fails if period is not positive.
+func NewBollinger(period int, k float64) (*Bollinger, error) {
+	if err := checkPeriod(period); err != nil {
+		return nil, err
+	}
+	return &Bollinger{k: k, cur: window{size: period}}, nil
+}
+
+// Update adds a candle and returns the bands, and if they are ready.
+func (bands *Bollinger) Update(candle models.Candle) (BollingerValue, bool) {
+	if bands.replaces(candle) {
+		bands.cur = bands.prev.clone()
+	} else {
+		bands.prev = bands.cur.clone()
+	}
+	bands.cur.add(parse(candle.Close))
+	return bands.Value()
+}
+
+// Value returns the current bands, and if they are ready.
+func (bands *Bollinger) Value() (BollingerValue, bool) {
+	if !bands.cur.full() {
+		return BollingerValue{}, false
+	}
+	mean := bands.cur.mean()
+	variance := 0.0
+	for _, value := range bands.cur.values {
+		variance += (value - mean) * (value - mean)
+	}
+	deviation := math.Sqrt(variance / float64(len(bands.cur.values)))
+	return BollingerValue{
+		Middle: mean,
+		Upper:  mean + bands.k*deviation,
+		Lower:  mean - bands.k*deviation,
+	}, true
+}
+
+type atrState struct {
+	prevClose float64
+	count     int
+	value     float64
+}
+
+// ATR is the average true range, using Wilder's smoothing.
+// Uses the Max and Min of the candles as high and low.
+type ATR struct {
+	step
+	period    int
+	cur, prev atrState
+}
+
+// NewATR returns an average true range of the given period. Fails if
+// period is not positive.
+func NewATR(period int) (*ATR, error) {
+	if err := checkPeriod(period); err != nil {
+		return nil, err
+	}
+	return &ATR{period: period}, nil
+}
+
+// Update adds a candle and returns the average, and if it is ready.
+func (atr *ATR) Update(candle models.Candle) (float64, bool) {
+	if atr.replaces(candle) {
+		atr.cur = atr.prev
+	} else {
+		atr.prev = atr.cur
+	}
+	high, low, closePrice := parse(candle.Max), parse(candle.Min), parse(candle.Close)
+	trueRange := high - low
+	if atr.cur.count > 0 {
+		trueRange = math.Max(trueRange, math.Max(math.Abs(high-atr.cur.prevClose), math.Abs(low-atr.cur.prevClose)))
+	}
+	n := float64(atr.period)
+	atr.cur.count++
+	if atr.cur.count <= atr.period {
+		atr.cur.value += trueRange / n
+	} else {
+		atr.cur.value = (atr.cur.value*(n-1) + trueRange) / n
+	}
+	atr.cur.prevClose = closePrice
+	return atr.Value()
+}
+
+// Value returns the current average, and if it is ready.
+func (atr *ATR) Value() (float64, bool) {
+	return atr.cur.value, atr.cur.count >= atr.period
+}
+
+type vwapState struct {
+	volume      float64
+	volumeQuote float64
+}
+
+// VWAP is the volume weighted average price since its creation or its last
+// reset. Uses the quote volume of each candle over its volume, the exact
+// average price of its trades, and the typical price (max+min+close)/3 for
+// candles without a quote volume.
+type VWAP struct {
+	step
+	cur, prev vwapState
+}
+
+// NewVWAP returns a volume weighted average price.
+func NewVWAP() *VWAP {
+	return &VWAP{}
+}
+
+// Reset restarts the average, e.g. at the start of a session.
+func (vwap *VWAP) Reset() {
+	*vwap = VWAP{}
+}
+
+// Update adds a candle and returns the average, and if it is ready.
+func (vwap *VWAP) Update(candle models.Candle) (float64, bool) {
+	if vwap.replaces(candle) {
+		vwap.cur = vwap.prev
+	} else {
+		vwap.prev = vwap.cur
+	}
+	volume := parse(candle.Volume)
+	volumeQuote := parse(candle.VolumeQuote)
+	if candle.VolumeQuote == "" {
+		volumeQuote = volume * (parse(candle.Max) + parse(candle.Min) + parse(candle.Close)) / 3
+	}
+	vwap.cur.volume += volume
+	vwap.cur.volumeQuote += volumeQuote
+	return vwap.Value()
+}
+
+// Value returns the current average, and if it is ready.
+func (vwap *VWAP) Value() (float64, bool) {
+	if vwap.cur.volume == 0 {
+		return 0, false
+	}
+	return vwap.cur.volumeQuote / vwap.cur.volume, true
+}
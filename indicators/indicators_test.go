@@ -0,0 +1,175 @@
+package indicators
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/cryptomarket/cryptomarket-go/models"
+)
+
+func candleOf(minute int, close float64) models.Candle {
+	price := fmt.Sprint(close)
+	return models.Candle{
+		Timestamp:   fmt.Sprintf("2021-01-20T20:%02d:00.000Z", minute),
+		Open:        price,
+		Close:       price,
+		Min:         fmt.Sprint(close - 1),
+		Max:         fmt.Sprint(close + 1),
+		Volume:      "2",
+		VolumeQuote: fmt.Sprint(2 * close),
+	}
+}
+
+func candlesOf(closes ...float64) []models.Candle {
+	candles := make([]models.Candle, len(closes))
+	for idx, close := range closes {
+		candles[idx] = candleOf(idx, close)
+	}
+	return candles
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestSMA(t *testing.T) {
+	values, err := SMAValues(candlesOf(1, 2, 3, 4), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !math.IsNaN(values[1]) || !near(values[2], 2) || !near(values[3], 3) {
+		t.Fatalf("wrong values: %v", values)
+	}
+}
+
+func TestEMA(t *testing.T) {
+	values, err := EMAValues(candlesOf(1, 2, 3, 4), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// seeded with the average 2, then 4*0.5 + 2*0.5
+	if !near(values[2], 2) || !near(values[3], 3) {
+		t.Fatalf("wrong values: %v", values)
+	}
+}
+
+func TestRSI(t *testing.T) {
+	values, err := RSIValues(candlesOf(1, 2, 3, 2), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// first average over the changes +1 +1, then gain 1*1/2 and loss 1/2
+	if !math.IsNaN(values[1]) || !near(values[2], 100) || !near(values[3], 50) {
+		t.Fatalf("wrong values: %v", values)
+	}
+}
+
+func TestMACD(t *testing.T) {
+	values, err := MACDValues(candlesOf(1, 2, 3, 4, 5, 6), 2, 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := values[len(values)-1]
+	if math.IsNaN(last.MACD) || !near(last.Histogram, last.MACD-last.Signal) {
+		t.Fatalf("wrong values: %v", values)
+	}
+	if !math.IsNaN(values[2].MACD) {
+		t.Fatalf("macd should not be ready: %v", values[2])
+	}
+}
+
+func TestBollinger(t *testing.T) {
+	values, err := BollingerValues(candlesOf(1, 3), 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !near(values[1].Middle, 2) || !near(values[1].Upper, 4) || !near(values[1].Lower, 0) {
+		t.Fatalf("wrong values: %v", values)
+	}
+}
+
+func TestATR(t *testing.T) {
+	values, err := ATRValues(candlesOf(10, 13, 13), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// true ranges 2, 4 (14-10), 2
+	if !near(values[1], 3) || !near(values[2], 2.5) {
+		t.Fatalf("wrong values: %v", values)
+	}
+}
+
+func TestVWAP(t *testing.T) {
+	values := VWAPValues(candlesOf(1, 3))
+	if !near(values[1], 2) {
+		t.Fatalf("wrong values: %v", values)
+	}
+}
+
+func TestReplaceOpenCandle(t *testing.T) {
+	sma, err := NewSMA(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sma.Update(candleOf(0, 1))
+	sma.Update(candleOf(1, 5))
+	// the open candle changes twice
+	sma.Update(candleOf(2, 7))
+	value, ok := sma.Update(candleOf(2, 3))
+	if !ok || !near(value, 4) {
+		t.Fatalf("wrong value after replacing the open candle: %v", value)
+	}
+	rsi, err := NewRSI(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, candle := range candlesOf(1, 2, 3) {
+		rsi.Update(candle)
+	}
+	rsi.Update(candleOf(3, 10))
+	replaced, _ := rsi.Update(candleOf(3, 2))
+	fresh, err := RSIValues(candlesOf(1, 2, 3, 2), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := fresh[3]
+	if !near(replaced, expected) {
+		t.Fatalf("replacing should match a fresh computation: %v %v", replaced, expected)
+	}
+}
+
+func TestSMAWithoutDrift(t *testing.T) {
+	// 1e17 absorbs the small values in a running sum
+	values, err := SMAValues(candlesOf(1e17, 1, 2, 3, 4, 5, 6), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values[6] != 5 {
+		t.Fatalf("the sum drifted: %v", values)
+	}
+	values, err = SMAValues([]models.Candle{candleOf(0, 1), {Timestamp: "2021-01-20T20:01:00.000Z", Close: "x"}, candleOf(2, 3), candleOf(3, 4), candleOf(4, 5)}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !math.IsNaN(values[2]) || values[4] != 4.5 {
+		t.Fatalf("the NaN stayed in the sum: %v", values)
+	}
+}
+
+func TestNonPositivePeriod(t *testing.T) {
+	constructors := map[string]func() error{
+		"SMA":       func() error { _, err := NewSMA(0); return err },
+		"EMA":       func() error { _, err := NewEMA(-1); return err },
+		"RSI":       func() error { _, err := NewRSI(0); return err },
+		"MACD":      func() error { _, err := NewMACD(12, 0, 9); return err },
+		"Bollinger": func() error { _, err := NewBollinger(0, 2); return err },
+		"ATR":       func() error { _, err := NewATR(-14); return err },
+		"SMAValues": func() error { _, err := SMAValues(candlesOf(1, 2), 0); return err },
+	}
+	for name, constructor := range constructors {
+		if err := constructor(); err == nil {
+			t.Errorf("%v: expected an invalid period error", name)
+		}
+	}
+}
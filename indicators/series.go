@@ -0,0 +1,102 @@
+package indicators
+
+import (
+	"math"
+
+	"github.com/cryptomarket/cryptomarket-go/models"
+)
+
+// the functions of this file compute an indicator over candles sorted oldest
+// first, returning a value for each candle. Values are NaN while the indicator
+// is not ready. They fail on an invalid period, like the constructors.
+
+type floatIndicator interface {
+	Update(candle models.Candle) (float64, bool)
+}
+
+func floatValues(indicator floatIndicator, candles []models.Candle) []float64 {
+	values := make([]float64, len(candles))
+	for idx, candle := range candles {
+		value, ok := indicator.Update(candle)
+		if !ok {
+			value = math.NaN()
+		}
+		values[idx] = value
+	}
+	return values
+}
+
+// SMAValues returns the simple moving average of each candle.
+func SMAValues(candles []models.Candle, period int) ([]float64, error) {
+	indicator, err := NewSMA(period)
+	if err != nil {
+		return nil, err
+	}
+	return floatValues(indicator, candles), nil
+}
+
+// EMAValues returns the exponential moving average of each candle.
+func EMAValues(candles []models.Candle, period int) ([]float64, error) {
+	indicator, err := NewEMA(period)
+	if err != nil {
+		return nil, err
+	}
+	return floatValues(indicator, candles), nil
+}
+
+// RSIValues returns the relative strength index of each candle.
+func RSIValues(candles []models.Candle, period int) ([]float64, error) {
+	indicator, err := NewRSI(period)
+	if err != nil {
+		return nil, err
+	}
+	return floatValues(indicator, candles), nil
+}
+
+// ATRValues returns the average true range of each candle.
+func ATRValues(candles []models.Candle, period int) ([]float64, error) {
+	indicator, err := NewATR(period)
+	if err != nil {
+		return nil, err
+	}
+	return floatValues(indicator, candles), nil
+}
+
+// VWAPValues returns the volume weighted average price up to each candle.
+func VWAPValues(candles []models.Candle) []float64 {
+	return floatValues(NewVWAP(), candles)
+}
+
+// MACDValues returns the MACD of each candle.
+func MACDValues(candles []models.Candle, fast, slow, signal int) ([]MACDValue, error) {
+	macd, err := NewMACD(fast, slow, signal)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]MACDValue, len(candles))
+	for idx, candle := range candles {
+		value, ok := macd.Update(candle)
+		if !ok {
+			value = MACDValue{MACD: math.NaN(), Signal: math.NaN(), Histogram: math.NaN()}
+		}
+		values[idx] = value
+	}
+	return values, nil
+}
+
+// BollingerValues returns the Bollinger bands of each candle.
+func BollingerValues(candles []models.Candle, period int, k float64) ([]BollingerValue, error) {
+	bands, err := NewBollinger(period, k)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]BollingerValue, len(candles))
+	for idx, candle := range candles {
+		value, ok := bands.Update(candle)
+		if !ok {
+			value = BollingerValue{Middle: math.NaN(), Upper: math.NaN(), Lower: math.NaN()}
+		}
+		values[idx] = value
+	}
+	return values, nil
+}
@@ -0,0 +1,22 @@
+// Package decimal holds the decimal helpers shared by the packages of the sdk
+// doing exact math over the decimal strings of the exchange.
+package decimal
+
+import (
+	"math/big"
+	"strings"
+)
+
+// Format formats a rational as a decimal string, rounded to places decimal
+// places and without trailing zeros.
+func Format(rat *big.Rat, places int) string {
+	str := rat.FloatString(places)
+	if strings.Contains(str, ".") {
+		str = strings.TrimRight(str, "0")
+		str = strings.TrimSuffix(str, ".")
+	}
+	if str == "-0" {
+		return "0"
+	}
+	return str
+}
@@ -0,0 +1,24 @@
+package decimal
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	cases := []struct {
+		rat      *big.Rat
+		expected string
+	}{
+		{big.NewRat(3, 2), "1.5"},
+		{big.NewRat(10, 1), "10"},
+		{big.NewRat(1, 3), "0.333333333333333333"},
+		{big.NewRat(-1, 3000000000000000000), "0"},
+		{big.NewRat(-5, 4), "-1.25"},
+	}
+	for _, c := range cases {
+		if str := Format(c.rat, 18); str != c.expected {
+			t.Errorf("%v: got %v, expected %v", c.rat, str, c.expected)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+// Package logging defines the logger used by the clients of the sdk.
+//
+// Logger has the method set of *slog.Logger, so a slog logger can be passed
+// as is. By default the clients log nothing.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Logger logs messages with key/value pairs, as in
+//
+//  logger.Info("connecting", "url", url)
+type Logger interface {
+	Debug(msg string, keyvals ...interface{})
+	Info(msg string, keyvals ...interface{})
+	Warn(msg string, keyvals ...interface{})
+	Error(msg string, keyvals ...interface{})
+}
+
+// Level is the severity of a message.
+type Level int
+
+// levels of the messages, with the values of the slog levels
+const (
+	LevelDebug Level = -4
+	LevelInfo  Level = 0
+	LevelWarn  Level = 4
+	LevelError Level = 8
+)
+
+func (level Level) String() string {
+	switch level {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	}
+	return "LEVEL(" + strconv.Itoa(int(level)) + ")"
+}
+
+// Discard is a logger that logs nothing.
+var Discard Logger = discard{}
+
+type discard struct{}
+
+func (discard) Debug(msg string, keyvals ...interface{}) {}
+func (discard) Info(msg string, keyvals ...interface{})  {}
+func (discard) Warn(msg string, keyvals ...interface{})  {}
+func (discard) Error(msg string, keyvals ...interface{}) {}
+
+// textLogger writes logfmt lines.
+type textLogger struct {
+	mutex  *sync.Mutex
+	w      io.Writer
+	level  Level
+	fields []interface{}
+	now    func() time.Time
+}
+
+// New returns a logger writing the messages of level and above to w as
+// logfmt lines, as the text handler of slog does:
+//
+//  time=2021-01-20T20:01:00.612Z level=INFO msg=connecting url=wss://...
+func New(w io.Writer, level Level) Logger {
+	return &textLogger{mutex: &sync.Mutex{}, w: w, level: level, now: time.Now}
+}
+
+// With returns a logger adding keyvals to every message of logger. Loggers
+// other than the ones of New get the fields prepended to every call.
+func With(logger Logger, keyvals ...interface{}) Logger {
+	if text, ok := logger.(*textLogger); ok {
+		with := *text
+		with.fields = append(append([]interface{}{}, text.fields...), keyvals...)
+		return &with
+	}
+	return withLogger{logger, keyvals}
+}
+
+func (logger *textLogger) Debug(msg string, keyvals ...interface{}) {
+	logger.log(LevelDebug, msg, keyvals)
+}
+
+func (logger *textLogger) Info(msg string, keyvals ...interface{}) {
+	logger.log(LevelInfo, msg, keyvals)
+}
+
+func (logger *textLogger) Warn(msg string, keyvals ...interface{}) {
+	logger.log(LevelWarn, msg, keyvals)
+}
+
+func (logger *textLogger) Error(msg string, keyvals ...interface{}) {
+	logger.log(LevelError, msg, keyvals)
+}
+
+func (logger *textLogger) log(level Level, msg string, keyvals []interface{}) {
+	if level < logger.level {
+		return
+	}
+	var line strings.Builder
+	line.WriteString("time=" + logger.now().UTC().Format("2006-01-02T15:04:05.000Z07:00"))
+	line.WriteString(" level=" + level.String())
+	line.WriteString(" msg=" + quote(msg))
+	writePairs(&line, logger.fields)
+	writePairs(&line, keyvals)
+	line.WriteString("\n")
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+	io.WriteString(logger.w, line.String())
+}
+
+func writePairs(line *strings.Builder, keyvals []interface{}) {
+	for idx := 0; idx < len(keyvals); idx += 2 {
+		if idx+1 == len(keyvals) {
+			// a value without key
+			line.WriteString(" !BADKEY=" + quote(fmt.Sprint(keyvals[idx])))
+			return
+		}
+		line.WriteString(" " + fmt.Sprint(keyvals[idx]) + "=" + quote(fmt.Sprint(keyvals[idx+1])))
+	}
+}
+
+// quote quotes the values with spaces, quotes or equal signs.
+func quote(value string) string {
+	if value == "" || strings.ContainsAny(value, " \"=\t\n") {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+type withLogger struct {
+	logger Logger
+	fields []interface{}
+}
+
+func (with withLogger) Debug(msg string, keyvals ...interface{}) {
+	with.logger.Debug(msg, append(append([]interface{}{}, with.fields...), keyvals...)...)
+}
+
+func (with withLogger) Info(msg string, keyvals ...interface{}) {
+	with.logger.Info(msg, append(append([]interface{}{}, with.fields...), keyvals...)...)
+}
+
+func (with withLogger) Warn(msg string, keyvals ...interface{}) {
+	with.logger.Warn(msg, append(append([]interface{}{}, with.fields...), keyvals...)...)
+}
+
+func (with withLogger) Error(msg string, keyvals ...interface{}) {
+	with.logger.Error(msg, append(append([]interface{}{}, with.fields...), keyvals...)...)
+}
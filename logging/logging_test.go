@@ -0,0 +1,38 @@
+package logging
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, LevelInfo).(*textLogger)
+	logger.now = func() time.Time { return time.Date(2021, 1, 20, 20, 1, 0, 612e6, time.UTC) }
+	logger.Debug("hidden")
+	logger.Info("connecting", "url", "wss://api.exchange.cryptomkt.com/api/2/ws/public")
+	With(logger, "path", "/api/2/ws/trading").Error("write failed", "error", "broken pipe", "odd")
+	expected := "time=2021-01-20T20:01:00.612Z level=INFO msg=connecting url=wss://api.exchange.cryptomkt.com/api/2/ws/public\n" +
+		"time=2021-01-20T20:01:00.612Z level=ERROR msg=\"write failed\" path=/api/2/ws/trading error=\"broken pipe\" !BADKEY=odd\n"
+	if buf.String() != expected {
+		t.Fatalf("got\n%s\nexpected\n%s", buf.String(), expected)
+	}
+}
+
+type recorder struct {
+	Logger
+	keyvals []interface{}
+}
+
+func (r *recorder) Warn(msg string, keyvals ...interface{}) {
+	r.keyvals = keyvals
+}
+
+func TestWith(t *testing.T) {
+	r := &recorder{Logger: Discard}
+	With(r, "client", "rest").Warn("retry", "attempt", 2)
+	if len(r.keyvals) != 4 || r.keyvals[0] != "client" || r.keyvals[3] != 2 {
+		t.Fatalf("wrong fields: %v", r.keyvals)
+	}
+}
@@ -0,0 +1,234 @@
+// Package metrics defines the metrics recorded by the clients of the sdk,
+// and a registry exporting them in the Prometheus text format.
+//
+// Metrics are optional: the clients record nothing unless given a Recorder.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Labels are the labels of a sample.
+type Labels map[string]string
+
+// Recorder records samples. A name is always used with the same kind of
+// metric, the first one it was used with.
+type Recorder interface {
+	// Add adds value to a counter.
+	Add(name string, labels Labels, value float64)
+	// Set sets a gauge.
+	Set(name string, labels Labels, value float64)
+	// Observe adds a value to a histogram, e.g. a latency in seconds.
+	Observe(name string, labels Labels, value float64)
+}
+
+// Discard is a recorder that records nothing.
+var Discard Recorder = discard{}
+
+type discard struct{}
+
+func (discard) Add(name string, labels Labels, value float64)     {}
+func (discard) Set(name string, labels Labels, value float64)     {}
+func (discard) Observe(name string, labels Labels, value float64) {}
+
+// DefaultBuckets are the upper bounds of the histograms of a registry, in
+// seconds.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+const (
+	kindCounter   = "counter"
+	kindGauge     = "gauge"
+	kindHistogram = "histogram"
+)
+
+// Registry keeps the samples in memory, and exports them in the Prometheus
+// text format. It is safe for concurrent use.
+type Registry struct {
+	mutex    sync.Mutex
+	families map[string]*family
+	buckets  []float64
+}
+
+type family struct {
+	kind   string
+	series map[string]*series // by encoded labels
+}
+
+type series struct {
+	value   float64  // of counters and gauges
+	counts  []uint64 // of histograms, per bucket, not cumulative
+	sum     float64
+	samples uint64
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family), buckets: DefaultBuckets}
+}
+
+func (registry *Registry) get(kind, name string, labels Labels) *series {
+	fam, ok := registry.families[name]
+	if !ok {
+		fam = &family{kind: kind, series: make(map[string]*series)}
+		registry.families[name] = fam
+	}
+	if fam.kind != kind {
+		return nil
+	}
+	key := encodeLabels(labels)
+	s, ok := fam.series[key]
+	if !ok {
+		s = &series{}
+		if kind == kindHistogram {
+			s.counts = make([]uint64, len(registry.buckets))
+		}
+		fam.series[key] = s
+	}
+	return s
+}
+
+// Add adds value to a counter.
+func (registry *Registry) Add(name string, labels Labels, value float64) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	if s := registry.get(kindCounter, name, labels); s != nil {
+		s.value += value
+	}
+}
+
+// Set sets a gauge.
+func (registry *Registry) Set(name string, labels Labels, value float64) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	if s := registry.get(kindGauge, name, labels); s != nil {
+		s.value = value
+	}
+}
+
+// Observe adds a value to a histogram.
+func (registry *Registry) Observe(name string, labels Labels, value float64) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	s := registry.get(kindHistogram, name, labels)
+	if s == nil {
+		return
+	}
+	if idx := sort.SearchFloat64s(registry.buckets, value); idx < len(s.counts) {
+		s.counts[idx]++
+	}
+	s.sum += value
+	s.samples++
+}
+
+// Value returns the value of a counter or a gauge, or the number of samples
+// of a histogram.
+func (registry *Registry) Value(name string, labels Labels) float64 {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	fam, ok := registry.families[name]
+	if !ok {
+		return 0
+	}
+	s, ok := fam.series[encodeLabels(labels)]
+	if !ok {
+		return 0
+	}
+	if fam.kind == kindHistogram {
+		return float64(s.samples)
+	}
+	return s.value
+}
+
+// WriteTo writes the metrics in the Prometheus text format, sorted by name
+// and labels.
+func (registry *Registry) WriteTo(w io.Writer) (int64, error) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	var out strings.Builder
+	names := make([]string, 0, len(registry.families))
+	for name := range registry.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fam := registry.families[name]
+		fmt.Fprintf(&out, "# TYPE %s %s\n", name, fam.kind)
+		keys := make([]string, 0, len(fam.series))
+		for key := range fam.series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			s := fam.series[key]
+			if fam.kind != kindHistogram {
+				fmt.Fprintf(&out, "%s%s %s\n", name, key, formatFloat(s.value))
+				continue
+			}
+			var cumulative uint64
+			for idx, bound := range registry.buckets {
+				cumulative += s.counts[idx]
+				fmt.Fprintf(&out, "%s_bucket%s %d\n", name, withLabel(key, "le", formatFloat(bound)), cumulative)
+			}
+			fmt.Fprintf(&out, "%s_bucket%s %d\n", name, withLabel(key, "le", "+Inf"), s.samples)
+			fmt.Fprintf(&out, "%s_sum%s %s\n", name, key, formatFloat(s.sum))
+			fmt.Fprintf(&out, "%s_count%s %d\n", name, key, s.samples)
+		}
+	}
+	n, err := io.WriteString(w, out.String())
+	return int64(n), err
+}
+
+// ServeHTTP serves the metrics to a Prometheus scraper.
+func (registry *Registry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	registry.WriteTo(w)
+}
+
+// encodeLabels encodes labels as {a="x",b="y"}, sorted by name.
+func encodeLabels(labels Labels) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, name+"="+quoteLabel(labels[name]))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// withLabel adds a label to encoded labels.
+func withLabel(encoded, name, value string) string {
+	pair := name + "=" + quoteLabel(value)
+	if encoded == "" {
+		return "{" + pair + "}"
+	}
+	return encoded[:len(encoded)-1] + "," + pair + "}"
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quoteLabel(value string) string {
+	return `"` + labelEscaper.Replace(value) + `"`
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+	registry.Add("requests_total", Labels{"status": "ok", "endpoint": "public/ticker"}, 1)
+	registry.Add("requests_total", Labels{"endpoint": "public/ticker", "status": "ok"}, 2)
+	registry.Set("pending", nil, 3)
+	registry.Observe("duration_seconds", Labels{"path": `a"b`}, 0.02)
+	registry.Observe("duration_seconds", Labels{"path": `a"b`}, 20)
+	registry.Set("requests_total", nil, 10) // a counter, ignored
+
+	var buf bytes.Buffer
+	registry.WriteTo(&buf)
+	expected := []string{
+		`# TYPE duration_seconds histogram`,
+		`duration_seconds_bucket{path="a\"b",le="0.01"} 0`,
+		`duration_seconds_bucket{path="a\"b",le="0.025"} 1`,
+		`duration_seconds_bucket{path="a\"b",le="10"} 1`,
+		`duration_seconds_bucket{path="a\"b",le="+Inf"} 2`,
+		`duration_seconds_sum{path="a\"b"} 20.02`,
+		`duration_seconds_count{path="a\"b"} 2`,
+		`# TYPE pending gauge`,
+		`pending 3`,
+		`# TYPE requests_total counter`,
+		`requests_total{endpoint="public/ticker",status="ok"} 3`,
+	}
+	lines := strings.Split(buf.String(), "\n")
+	for _, line := range expected {
+		found := false
+		for _, got := range lines {
+			found = found || got == line
+		}
+		if !found {
+			t.Errorf("missing line %v in\n%v", line, buf.String())
+		}
+	}
+	if registry.Value("requests_total", Labels{"endpoint": "public/ticker", "status": "ok"}) != 3 {
+		t.Error("wrong value")
+	}
+
+	recorder := httptest.NewRecorder()
+	registry.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	if recorder.Body.String() != buf.String() || !strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain") {
+		t.Error("wrong http response")
+	}
+}
@@ -0,0 +1,38 @@
+package metrics
+
+// names of the metrics recorded by the sdk
+const (
+	// RESTRequests counts the rest requests, labeled by method, endpoint,
+	// status: ok, api_error or error, and code: the http status code, empty
+	// without a response.
+	RESTRequests = "cryptomarket_rest_requests_total"
+	// RESTRequestDuration observes the latency of the rest requests in
+	// seconds, labeled by method and endpoint.
+	RESTRequestDuration = "cryptomarket_rest_request_duration_seconds"
+
+	// DownloadRetries counts the retried requests of a downloader, labeled by
+	// kind: candles or trades.
+	DownloadRetries = "cryptomarket_download_retries_total"
+	// DownloadLimiterWait observes the time spent waiting for the rate limit
+	// of a downloader, in seconds.
+	DownloadLimiterWait = "cryptomarket_download_limiter_wait_seconds"
+
+	// WSMessages counts the feed messages received, labeled by path and feed.
+	WSMessages = "cryptomarket_ws_messages_total"
+	// WSDisconnects counts the connections lost, labeled by path. The
+	// clients don't reconnect by themselves.
+	WSDisconnects = "cryptomarket_ws_disconnects_total"
+	// WSPendingRequests is the number of requests waiting for a response,
+	// labeled by path.
+	WSPendingRequests = "cryptomarket_ws_pending_requests"
+	// WSOrderbookResyncs counts the snapshots requested after a sequence
+	// gap, by a BookManager or an order book subscription, labeled by path
+	// and symbol.
+	WSOrderbookResyncs = "cryptomarket_ws_orderbook_resyncs_total"
+	// WSOrderbookGaps counts the sequence gaps found by a BookManager or an
+	// order book subscription, labeled by path and symbol.
+	WSOrderbookGaps = "cryptomarket_ws_orderbook_gaps_total"
+	// WSFeedDrops counts the messages dropped by the overflow policy of a
+	// subscription, labeled by path and feed.
+	WSFeedDrops = "cryptomarket_ws_feed_drops_total"
+)
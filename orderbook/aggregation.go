@@ -0,0 +1,99 @@
+package orderbook
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/cryptomarket/cryptomarket-go/internal/decimal"
+	"github.com/cryptomarket/cryptomarket-go/models"
+)
+
+// Truncate returns a copy of the book with at most depth levels on each side.
+// A depth of 0 keeps all the levels.
+func Truncate(ob *models.OrderBook, depth int) *models.OrderBook {
+	result := *ob
+	result.Ask = truncateSide(ob.Ask, depth)
+	result.Bid = truncateSide(ob.Bid, depth)
+	return &result
+}
+
+func truncateSide(levels []models.BookLevel, depth int) []models.BookLevel {
+	if depth > 0 && len(levels) > depth {
+		levels = levels[:depth]
+	}
+	return append([]models.BookLevel(nil), levels...)
+}
+
+// EqualLevels reports if two sides of books have the same levels, in the same
+// order. Prices and sizes are compared as strings, as sent by the exchange.
+func EqualLevels(a, b []models.BookLevel) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for idx := range a {
+		if a[idx] != b[idx] {
+			return false
+		}
+	}
+	return true
+}
+
+// Aggregate returns a copy of the book with its levels grouped into price
+// buckets of size step, usually a multiple of the tick size of the symbol.
+//
+// Asks are grouped at the upper bound of their bucket and bids at the lower
+// bound, so a grouped level never shows a better price than the levels it holds.
+func Aggregate(ob *models.OrderBook, step string) (*models.OrderBook, error) {
+	bucket, err := parseDecimal(step)
+	if err != nil {
+		return nil, err
+	}
+	if bucket.Sign() <= 0 {
+		return nil, fmt.Errorf("CryptomarketSDKError: invalid price step: %q", step)
+	}
+	result := *ob
+	if result.Ask, err = aggregateSide(ob.Ask, bucket, true); err != nil {
+		return nil, err
+	}
+	if result.Bid, err = aggregateSide(ob.Bid, bucket, false); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// aggregateSide groups the sorted levels of a side. As buckets are monotonic
+// on price, levels of a bucket are always contiguous.
+func aggregateSide(levels []models.BookLevel, bucket *big.Rat, roundUp bool) ([]models.BookLevel, error) {
+	parsed, err := parseLevels(levels)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]models.BookLevel, 0)
+	var current, size *big.Rat
+	for _, lvl := range parsed {
+		price := bucketPrice(lvl.price, bucket, roundUp)
+		if current != nil && current.Cmp(price) == 0 {
+			size.Add(size, lvl.size)
+			continue
+		}
+		if current != nil {
+			result = append(result, models.BookLevel{Price: decimal.Format(current, Precision), Size: decimal.Format(size, Precision)})
+		}
+		current, size = price, new(big.Rat).Set(lvl.size)
+	}
+	if current != nil {
+		result = append(result, models.BookLevel{Price: decimal.Format(current, Precision), Size: decimal.Format(size, Precision)})
+	}
+	return result, nil
+}
+
+// bucketPrice rounds a price to a multiple of bucket, up or down.
+func bucketPrice(price, bucket *big.Rat, roundUp bool) *big.Rat {
+	quotient := new(big.Rat).Quo(price, bucket)
+	// euclidean division, the floor of the quotient for positive prices
+	multiple := new(big.Int).Div(quotient.Num(), quotient.Denom())
+	if roundUp && !quotient.IsInt() {
+		multiple.Add(multiple, big.NewInt(1))
+	}
+	return new(big.Rat).Mul(new(big.Rat).SetInt(multiple), bucket)
+}
@@ -0,0 +1,45 @@
+package orderbook
+
+import (
+	"testing"
+
+	"github.com/cryptomarket/cryptomarket-go/models"
+)
+
+func TestTruncate(t *testing.T) {
+	ob := testBook()
+	result := Truncate(ob, 2)
+	if len(result.Ask) != 2 || len(result.Bid) != 2 {
+		t.Fatalf("wrong depth: %v %v", result.Ask, result.Bid)
+	}
+	if len(Truncate(ob, 0).Ask) != 3 {
+		t.Fatal("depth of 0 should keep all levels")
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	ob := &models.OrderBook{
+		Ask: []models.BookLevel{
+			{Price: "100.01", Size: "1"},
+			{Price: "100.1", Size: "2"},
+			{Price: "100.15", Size: "0.5"},
+		},
+		Bid: []models.BookLevel{
+			{Price: "99.99", Size: "1"},
+			{Price: "99.91", Size: "2"},
+			{Price: "99.9", Size: "3"},
+		},
+	}
+	result, err := Aggregate(ob, "0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedAsk := []models.BookLevel{{Price: "100.1", Size: "3"}, {Price: "100.2", Size: "0.5"}}
+	expectedBid := []models.BookLevel{{Price: "99.9", Size: "6"}}
+	if !EqualLevels(result.Ask, expectedAsk) || !EqualLevels(result.Bid, expectedBid) {
+		t.Fatalf("wrong aggregation: ask %v bid %v", result.Ask, result.Bid)
+	}
+	if _, err = Aggregate(ob, "0"); err == nil {
+		t.Fatal("should fail with a zero step")
+	}
+}
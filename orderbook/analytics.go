@@ -0,0 +1,351 @@
+// Package orderbook computes analytics over order books, such as the average
+// fill price of an order, its slippage and the depth of the book around its mid
+// price, and builds reduced views of them, limited in depth or grouped by price.
+//
+// All the computations use exact decimal math over the strings of the book
+// levels. Sums and products are exact, quotients are rounded to Precision
+// decimal places. Results are returned as decimal strings without trailing zeros.
+package orderbook
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/cryptomarket/cryptomarket-go/internal/decimal"
+	"github.com/cryptomarket/cryptomarket-go/models"
+)
+
+// Precision is the number of decimal places of the results that involve a division.
+const Precision = 18
+
+var (
+	errEmptySide    = errors.New("CryptomarketSDKError: empty side of orderbook")
+	errInvalidValue = errors.New("CryptomarketSDKError: invalid decimal value")
+)
+
+// Fill is the result of walking a side of an order book to fill an order.
+type Fill struct {
+	Quantity     string // base currency quantity filled
+	Amount       string // quote currency amount paid or received
+	AveragePrice string // Amount over Quantity
+	WorstPrice   string // price of the last level reached
+	Complete     bool   // false if the side had not enough depth for the order
+}
+
+// DepthRange is the cumulative size and amount of the book within a range of prices.
+type DepthRange struct {
+	BidSize   string // base currency size of the bids in range
+	BidAmount string // quote currency amount of the bids in range
+	AskSize   string // base currency size of the asks in range
+	AskAmount string // quote currency amount of the asks in range
+}
+
+type level struct {
+	price *big.Rat
+	size  *big.Rat
+}
+
+func parseDecimal(value string) (*big.Rat, error) {
+	rat, ok := new(big.Rat).SetString(value)
+	if !ok {
+		return nil, fmt.Errorf("%v: %q", errInvalidValue, value)
+	}
+	return rat, nil
+}
+
+// parsePositive parses the quantity or amount of an order.
+func parsePositive(value string) (*big.Rat, error) {
+	rat, err := parseDecimal(value)
+	if err != nil {
+		return nil, err
+	}
+	if rat.Sign() <= 0 {
+		return nil, fmt.Errorf("CryptomarketSDKError: not a positive value: %q", value)
+	}
+	return rat, nil
+}
+
+func parseLevels(levels []models.BookLevel) ([]level, error) {
+	parsed := make([]level, 0, len(levels))
+	for _, bookLevel := range levels {
+		price, err := parseDecimal(bookLevel.Price)
+		if err != nil {
+			return nil, err
+		}
+		size, err := parseDecimal(bookLevel.Size)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, level{price: price, size: size})
+	}
+	return parsed, nil
+}
+
+// bookSide returns the side of the book an order of the given side executes
+// against: asks for buys and bids for sells.
+func bookSide(ob *models.OrderBook, side models.SideType) ([]level, error) {
+	switch side {
+	case models.SideTypeBuy:
+		return parseLevels(ob.Ask)
+	case models.SideTypeSell:
+		return parseLevels(ob.Bid)
+	}
+	return nil, fmt.Errorf("CryptomarketSDKError: invalid side: %q", side)
+}
+
+func midPrice(ob *models.OrderBook) (*big.Rat, error) {
+	if len(ob.Ask) == 0 || len(ob.Bid) == 0 {
+		return nil, errEmptySide
+	}
+	ask, err := parseDecimal(ob.Ask[0].Price)
+	if err != nil {
+		return nil, err
+	}
+	bid, err := parseDecimal(ob.Bid[0].Price)
+	if err != nil {
+		return nil, err
+	}
+	mid := new(big.Rat).Add(ask, bid)
+	return mid.Quo(mid, big.NewRat(2, 1)), nil
+}
+
+// Mid returns the mid price of the book, the average of the best ask and the best bid.
+func Mid(ob *models.OrderBook) (string, error) {
+	mid, err := midPrice(ob)
+	if err != nil {
+		return "", err
+	}
+	return decimal.Format(mid, Precision), nil
+}
+
+// Spread returns the difference between the best ask and the best bid.
+func Spread(ob *models.OrderBook) (string, error) {
+	if len(ob.Ask) == 0 || len(ob.Bid) == 0 {
+		return "", errEmptySide
+	}
+	ask, err := parseDecimal(ob.Ask[0].Price)
+	if err != nil {
+		return "", err
+	}
+	bid, err := parseDecimal(ob.Bid[0].Price)
+	if err != nil {
+		return "", err
+	}
+	return decimal.Format(new(big.Rat).Sub(ask, bid), Precision), nil
+}
+
+// fill walks the levels until the limit is reached. The limit is a base
+// quantity if byAmount is false, and a quote amount otherwise.
+func fill(levels []level, limit *big.Rat, byAmount bool) (quantity, amount, worst *big.Rat, complete bool) {
+	quantity, amount = new(big.Rat), new(big.Rat)
+	remaining := new(big.Rat).Set(limit)
+	for _, lvl := range levels {
+		if remaining.Sign() <= 0 {
+			break
+		}
+		levelAmount := new(big.Rat).Mul(lvl.price, lvl.size)
+		takeSize, takeAmount := lvl.size, levelAmount
+		if byAmount && levelAmount.Cmp(remaining) > 0 {
+			takeAmount = remaining
+			takeSize = new(big.Rat).Quo(remaining, lvl.price)
+		} else if !byAmount && lvl.size.Cmp(remaining) > 0 {
+			takeSize = remaining
+			takeAmount = new(big.Rat).Mul(remaining, lvl.price)
+		}
+		quantity.Add(quantity, takeSize)
+		amount.Add(amount, takeAmount)
+		if byAmount {
+			remaining.Sub(remaining, takeAmount)
+		} else {
+			remaining.Sub(remaining, takeSize)
+		}
+		worst = lvl.price
+	}
+	return quantity, amount, worst, remaining.Sign() <= 0
+}
+
+func newFill(quantity, amount, worst *big.Rat, complete bool) (*Fill, error) {
+	if worst == nil || quantity.Sign() == 0 {
+		return nil, errEmptySide
+	}
+	return &Fill{
+		Quantity:     decimal.Format(quantity, Precision),
+		Amount:       decimal.Format(amount, Precision),
+		AveragePrice: decimal.Format(new(big.Rat).Quo(amount, quantity), Precision),
+		WorstPrice:   decimal.Format(worst, Precision),
+		Complete:     complete,
+	}, nil
+}
+
+// FillQuantity walks the book to fill an order of the given side and base
+// currency quantity. Buys execute against the asks and sells against the bids.
+//
+// If the side has not enough depth the order is filled as much as possible
+// and the Complete field of the result is false.
+func FillQuantity(ob *models.OrderBook, side models.SideType, quantity string) (*Fill, error) {
+	limit, err := parsePositive(quantity)
+	if err != nil {
+		return nil, err
+	}
+	levels, err := bookSide(ob, side)
+	if err != nil {
+		return nil, err
+	}
+	return newFill(fill(levels, limit, false))
+}
+
+// FillAmount walks the book to fill an order of the given side worth the given
+// quote currency amount. Buys execute against the asks and sells against the bids.
+//
+// If the side has not enough depth the order is filled as much as possible
+// and the Complete field of the result is false.
+func FillAmount(ob *models.OrderBook, side models.SideType, amount string) (*Fill, error) {
+	limit, err := parsePositive(amount)
+	if err != nil {
+		return nil, err
+	}
+	levels, err := bookSide(ob, side)
+	if err != nil {
+		return nil, err
+	}
+	return newFill(fill(levels, limit, true))
+}
+
+// Slippage returns the expected slippage of an order of the given side and
+// quantity, as a fraction of the mid price. A positive slippage is a cost:
+// paying above mid for buys, and receiving below mid for sells.
+//
+// Returns an error if the book has not enough depth to fill the order, or
+// if the quantity is not positive.
+func Slippage(ob *models.OrderBook, side models.SideType, quantity string) (string, error) {
+	mid, err := midPrice(ob)
+	if err != nil {
+		return "", err
+	}
+	limit, err := parsePositive(quantity)
+	if err != nil {
+		return "", err
+	}
+	levels, err := bookSide(ob, side)
+	if err != nil {
+		return "", err
+	}
+	filled, amount, _, complete := fill(levels, limit, false)
+	if !complete {
+		return "", fmt.Errorf("CryptomarketSDKError: not enough depth to fill %v", quantity)
+	}
+	average := new(big.Rat).Quo(amount, filled)
+	slippage := new(big.Rat).Sub(average, mid)
+	if side == models.SideTypeSell {
+		slippage.Neg(slippage)
+	}
+	return decimal.Format(slippage.Quo(slippage, mid), Precision), nil
+}
+
+// Depth returns the cumulative size and amount of both sides of the book with
+// prices within percent of the mid price. The percent is given in percentage
+// points, "0.5" stands for ±0.5%.
+func Depth(ob *models.OrderBook, percent string) (*DepthRange, error) {
+	mid, err := midPrice(ob)
+	if err != nil {
+		return nil, err
+	}
+	pct, err := parseDecimal(percent)
+	if err != nil {
+		return nil, err
+	}
+	delta := new(big.Rat).Mul(mid, pct)
+	delta.Quo(delta, big.NewRat(100, 1))
+	low := new(big.Rat).Sub(mid, delta)
+	high := new(big.Rat).Add(mid, delta)
+
+	asks, err := parseLevels(ob.Ask)
+	if err != nil {
+		return nil, err
+	}
+	bids, err := parseLevels(ob.Bid)
+	if err != nil {
+		return nil, err
+	}
+	askSize, askAmount := sumWhile(asks, func(price *big.Rat) bool { return price.Cmp(high) <= 0 })
+	bidSize, bidAmount := sumWhile(bids, func(price *big.Rat) bool { return price.Cmp(low) >= 0 })
+	return &DepthRange{
+		BidSize:   decimal.Format(bidSize, Precision),
+		BidAmount: decimal.Format(bidAmount, Precision),
+		AskSize:   decimal.Format(askSize, Precision),
+		AskAmount: decimal.Format(askAmount, Precision),
+	}, nil
+}
+
+// sumWhile sums the sizes and amounts of the levels while inRange holds for their price.
+func sumWhile(levels []level, inRange func(*big.Rat) bool) (size, amount *big.Rat) {
+	size, amount = new(big.Rat), new(big.Rat)
+	for _, lvl := range levels {
+		if !inRange(lvl.price) {
+			break
+		}
+		size.Add(size, lvl.size)
+		amount.Add(amount, new(big.Rat).Mul(lvl.price, lvl.size))
+	}
+	return size, amount
+}
+
+// Imbalance returns the imbalance of the sizes of the top levels of the book,
+// (bids - asks) / (bids + asks), between -1 (only asks) and 1 (only bids).
+// A levels of 0 uses all the levels of the book.
+func Imbalance(ob *models.OrderBook, levels int) (string, error) {
+	asks, err := parseLevels(ob.Ask)
+	if err != nil {
+		return "", err
+	}
+	bids, err := parseLevels(ob.Bid)
+	if err != nil {
+		return "", err
+	}
+	if levels > 0 {
+		if len(asks) > levels {
+			asks = asks[:levels]
+		}
+		if len(bids) > levels {
+			bids = bids[:levels]
+		}
+	}
+	always := func(*big.Rat) bool { return true }
+	askSize, _ := sumWhile(asks, always)
+	bidSize, _ := sumWhile(bids, always)
+	total := new(big.Rat).Add(askSize, bidSize)
+	if total.Sign() == 0 {
+		return "", errEmptySide
+	}
+	imbalance := new(big.Rat).Sub(bidSize, askSize)
+	return decimal.Format(imbalance.Quo(imbalance, total), Precision), nil
+}
+
+// MarketDepth returns a copy of the book with the AskAveragePrice and
+// BidAveragePrice fields set to the average fill price of the given volume,
+// like the exchange does for the MarketDepthSearch request, without a network
+// round-trip. Average prices are left empty for sides without enough depth.
+func MarketDepth(ob *models.OrderBook, volume string) (*models.OrderBook, error) {
+	result := *ob
+	result.AskAveragePrice = ""
+	result.BidAveragePrice = ""
+	for _, side := range []models.SideType{models.SideTypeBuy, models.SideTypeSell} {
+		filled, err := FillQuantity(ob, side, volume)
+		if err == errEmptySide {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !filled.Complete {
+			continue
+		}
+		if side == models.SideTypeBuy {
+			result.AskAveragePrice = filled.AveragePrice
+		} else {
+			result.BidAveragePrice = filled.AveragePrice
+		}
+	}
+	return &result, nil
+}
@@ -0,0 +1,134 @@
+package orderbook
+
+import (
+	"testing"
+
+	"github.com/cryptomarket/cryptomarket-go/models"
+)
+
+func testBook() *models.OrderBook {
+	return &models.OrderBook{
+		Symbol: "EOSETH",
+		Ask: []models.BookLevel{
+			{Price: "101", Size: "1"},
+			{Price: "102", Size: "2"},
+			{Price: "110", Size: "5"},
+		},
+		Bid: []models.BookLevel{
+			{Price: "99", Size: "3"},
+			{Price: "98", Size: "1"},
+			{Price: "90", Size: "10"},
+		},
+	}
+}
+
+func TestMidAndSpread(t *testing.T) {
+	ob := testBook()
+	if mid, err := Mid(ob); err != nil || mid != "100" {
+		t.Fatalf("wrong mid: %v %v", mid, err)
+	}
+	if spread, err := Spread(ob); err != nil || spread != "2" {
+		t.Fatalf("wrong spread: %v %v", spread, err)
+	}
+	if _, err := Mid(&models.OrderBook{}); err == nil {
+		t.Fatal("should fail on an empty book")
+	}
+}
+
+func TestFillQuantity(t *testing.T) {
+	ob := testBook()
+	filled, err := FillQuantity(ob, models.SideTypeBuy, "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filled.AveragePrice != "101.5" || filled.Amount != "203" || filled.WorstPrice != "102" || !filled.Complete {
+		t.Fatalf("wrong fill: %+v", filled)
+	}
+	filled, err = FillQuantity(ob, models.SideTypeSell, "20")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filled.Complete || filled.Quantity != "14" {
+		t.Fatalf("should be an incomplete fill: %+v", filled)
+	}
+	if _, err = FillQuantity(ob, models.SideTypeBuy, "abc"); err == nil {
+		t.Fatal("should fail with an invalid quantity")
+	}
+}
+
+func TestFillAmount(t *testing.T) {
+	filled, err := FillAmount(testBook(), models.SideTypeBuy, "152")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filled.Quantity != "1.5" || filled.AveragePrice != "101.333333333333333333" {
+		t.Fatalf("wrong fill: %+v", filled)
+	}
+}
+
+func TestSlippage(t *testing.T) {
+	ob := testBook()
+	slippage, err := Slippage(ob, models.SideTypeBuy, "2")
+	if err != nil || slippage != "0.015" {
+		t.Fatalf("wrong buy slippage: %v %v", slippage, err)
+	}
+	slippage, err = Slippage(ob, models.SideTypeSell, "3")
+	if err != nil || slippage != "0.01" {
+		t.Fatalf("wrong sell slippage: %v %v", slippage, err)
+	}
+	if _, err = Slippage(ob, models.SideTypeBuy, "100"); err == nil {
+		t.Fatal("should fail without enough depth")
+	}
+}
+
+func TestNonPositiveOrders(t *testing.T) {
+	ob := testBook()
+	for _, value := range []string{"0", "-1", "0.000"} {
+		if _, err := FillQuantity(ob, models.SideTypeBuy, value); err == nil || err == errEmptySide {
+			t.Errorf("FillQuantity %v: expected an invalid quantity error, got %v", value, err)
+		}
+		if _, err := FillAmount(ob, models.SideTypeSell, value); err == nil || err == errEmptySide {
+			t.Errorf("FillAmount %v: expected an invalid amount error, got %v", value, err)
+		}
+		if _, err := Slippage(ob, models.SideTypeBuy, value); err == nil {
+			t.Errorf("Slippage %v: expected an invalid quantity error", value)
+		}
+	}
+}
+
+func TestDepth(t *testing.T) {
+	depth, err := Depth(testBook(), "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if depth.AskSize != "3" || depth.AskAmount != "305" || depth.BidSize != "4" || depth.BidAmount != "395" {
+		t.Fatalf("wrong depth: %+v", depth)
+	}
+}
+
+func TestImbalance(t *testing.T) {
+	ob := testBook()
+	if imbalance, err := Imbalance(ob, 1); err != nil || imbalance != "0.5" {
+		t.Fatalf("wrong imbalance: %v %v", imbalance, err)
+	}
+	if imbalance, err := Imbalance(ob, 0); err != nil || imbalance != "0.272727272727272727" {
+		t.Fatalf("wrong imbalance: %v %v", imbalance, err)
+	}
+}
+
+func TestMarketDepth(t *testing.T) {
+	result, err := MarketDepth(testBook(), "3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.AskAveragePrice != "101.666666666666666667" || result.BidAveragePrice != "99" {
+		t.Fatalf("wrong average prices: %v %v", result.AskAveragePrice, result.BidAveragePrice)
+	}
+	result, err = MarketDepth(testBook(), "100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.AskAveragePrice != "" || result.BidAveragePrice != "" {
+		t.Fatal("average prices should be empty without enough depth")
+	}
+}
@@ -0,0 +1,43 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/cryptomarket/cryptomarket-go/args"
+	"github.com/cryptomarket/cryptomarket-go/audit"
+)
+
+func TestAudit(t *testing.T) {
+	withFakeServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == methodGet {
+			w.Write([]byte(`[]`))
+			return
+		}
+		w.Write([]byte(`{"id":"6a2fb54d"}`))
+	})
+	var buf bytes.Buffer
+	client := NewClient("key", "secret", WithAudit(audit.NewLog(audit.WriterSink(&buf))))
+	if _, err := client.GetTradingBalance(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.WithdrawCrypto(context.Background(), args.Currency("ETH"), args.Amount("1"), args.Address("abc")); err != nil {
+		t.Fatal(err)
+	}
+	records, err := audit.ReadRecords(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = audit.Verify(records); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("expected only the withdrawal, got %v records", len(records))
+	}
+	record := records[0]
+	if record.Method != "POST "+endpointWithdrawCrypto || record.ResultID != "6a2fb54d" || record.Params["address"] == "abc" {
+		t.Fatalf("wrong record: %+v", record)
+	}
+}
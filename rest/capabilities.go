@@ -0,0 +1,36 @@
+package rest
+
+import (
+	"strings"
+
+	"github.com/cryptomarket/cryptomarket-go/auth"
+)
+
+// WithCapabilities restricts the client to a set of capabilities, e.g.
+// auth.ReadOnly. Requests out of them fail locally with an
+// *auth.CapabilityError, without reaching the exchange, whatever the
+// permissions of the api key. Default is auth.AllCapabilities.
+func WithCapabilities(capabilities auth.Capability) Option {
+	return func(client *Client) {
+		client.capabilities = capabilities
+	}
+}
+
+// requiredCapability returns the capability needed by a request.
+func requiredCapability(method, endpoint string) auth.Capability {
+	switch {
+	case strings.HasPrefix(endpoint, "public/"):
+		return auth.MarketData
+	case strings.HasPrefix(endpoint, endpointCryptoAddressIsMine),
+		strings.HasPrefix(endpoint, endpointEstimateWithdraw):
+		return auth.ReadAccount
+	case strings.HasPrefix(endpoint, endpointWithdrawCrypto):
+		return auth.Withdraw
+	case method == methodGet:
+		return auth.ReadAccount
+	case strings.HasPrefix(endpoint, endpointOrder):
+		return auth.Trade
+	}
+	// transfers, conversions and new deposit addresses
+	return auth.Transfer
+}
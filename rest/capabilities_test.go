@@ -0,0 +1,58 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/cryptomarket/cryptomarket-go/args"
+	"github.com/cryptomarket/cryptomarket-go/auth"
+)
+
+func TestRequiredCapability(t *testing.T) {
+	cases := []struct {
+		method, endpoint string
+		expected         auth.Capability
+	}{
+		{methodGet, endpointTicker + "/EOSETH", auth.MarketData},
+		{methodGet, endpointOrder, auth.ReadAccount},
+		{methodGet, endpointCryptoAdress + "/ETH", auth.ReadAccount},
+		{methodPost, endpointCryptoAdress + "/ETH", auth.Transfer},
+		{methodDelete, endpointCryptoAddressIsMine + "/abc", auth.ReadAccount},
+		{methodPost, endpointOrder, auth.Trade},
+		{methodDelete, endpointOrder + "/abc", auth.Trade},
+		{methodPost, endpointWithdrawCrypto, auth.Withdraw},
+		{methodPut, endpointWithdrawCrypto + "/abc", auth.Withdraw},
+		{methodPost, endpointAccountTranserInternal, auth.Transfer},
+		{methodPost, endpointTransferConvert, auth.Transfer},
+	}
+	for _, c := range cases {
+		if required := requiredCapability(c.method, c.endpoint); required != c.expected {
+			t.Errorf("%v %v requires %v, expected %v", c.method, c.endpoint, required, c.expected)
+		}
+	}
+}
+
+func TestReadOnlyClient(t *testing.T) {
+	requests := 0
+	withFakeServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`[]`))
+	})
+	client := NewClient("key", "secret", WithCapabilities(auth.ReadOnly))
+	if _, err := client.GetTradingBalance(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	_, err := client.WithdrawCrypto(context.Background(), args.Currency("ETH"), args.Amount("1"), args.Address("abc"))
+	var capabilityErr *auth.CapabilityError
+	if !errors.As(err, &capabilityErr) || capabilityErr.Required != auth.Withdraw {
+		t.Fatalf("expected a capability error, got %v", err)
+	}
+	if _, err = client.CancelAllOrders(context.Background()); !errors.As(err, &capabilityErr) {
+		t.Fatalf("expected a capability error, got %v", err)
+	}
+	if requests != 1 {
+		t.Fatalf("rejected requests reached the server: %v requests", requests)
+	}
+}
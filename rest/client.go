@@ -5,9 +5,16 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"path"
 	"strconv"
+	"strings"
 
 	"github.com/cryptomarket/cryptomarket-go/args"
+	"github.com/cryptomarket/cryptomarket-go/audit"
+	"github.com/cryptomarket/cryptomarket-go/auth"
+	"github.com/cryptomarket/cryptomarket-go/credentials"
+	"github.com/cryptomarket/cryptomarket-go/logging"
 
 	"github.com/cryptomarket/cryptomarket-go/models"
 )
@@ -33,19 +40,93 @@ const (
 
 // Client handles all the comunication with the rest API
 type Client struct {
-	hclient httpclient
+	hclient      httpclient
+	capabilities auth.Capability
+	audit        *audit.Log
+	middlewares  []Middleware
+	handler      Handler // the middlewares around the http client
+	logger       logging.Logger
 }
 
 // NewClient creates a new rest client to communicate with the exchange.
 // Requests to the exchange via this clients use the args package for aguments.
 // All requests accepts contexts for cancelation.
-func NewClient(apiKey, apiSecret string) (client *Client) {
+func NewClient(apiKey, apiSecret string, options ...Option) (client *Client) {
+	return NewClientWithSigner(auth.HS256(apiKey, apiSecret), options...)
+}
+
+// NewClientWithSigner creates a new rest client signing its requests with
+// signer, e.g. auth.Basic or an auth.External signer with the secret kept
+// out of the process.
+func NewClientWithSigner(signer auth.Signer, options ...Option) (client *Client) {
 	client = &Client{
-		hclient: newHTTPClient(apiKey, apiSecret),
+		hclient:      newHTTPClient(signer),
+		capabilities: auth.AllCapabilities,
+		logger:       logging.Discard,
+	}
+	for _, option := range options {
+		option(client)
 	}
+	client.handler = chain(client.middlewares, client.send)
 	return
 }
 
+// NewClientFromProvider creates a new rest client with the credentials of a
+// provider, such as credentials.Default("").
+func NewClientFromProvider(ctx context.Context, provider credentials.Provider, options ...Option) (*Client, error) {
+	keys, err := provider.Retrieve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(keys.APIKey, keys.APISecret, options...), nil
+}
+
+// SetCredentials replaces the api key and secret of the client, signing with
+// HS256. Requests already signed are not affected, the following ones use
+// the new credentials.
+func (client *Client) SetCredentials(apiKey, apiSecret string) {
+	client.SetSigner(auth.HS256(apiKey, apiSecret))
+}
+
+// SetSigner replaces the signer of the client. Requests already signed are
+// not affected, the following ones use the new signer.
+func (client *Client) SetSigner(signer auth.Signer) {
+	client.hclient.setSigner(signer)
+	client.logger.Info("signer replaced")
+}
+
+// Do makes a request to any endpoint of the exchange, e.g. one not wrapped
+// by the client yet, and decodes the response into result. endpoint is
+// relative to the api version, e.g. "public/ticker/EOSETH". Private requests
+// are signed, and all go through the capabilities, the middlewares and the
+// audit log of the client. Errors of the exchange are returned as in the
+// other methods. The endpoint is cleaned before checking its capability, and
+// endpoints out of the api version, like "../x", or with escaped characters,
+// like "%77", are rejected.
+func (client *Client) Do(ctx context.Context, httpMethod, endpoint string, params map[string]interface{}, public bool, result interface{}) error {
+	endpoint, err := cleanEndpoint(endpoint)
+	if err != nil {
+		return err
+	}
+	return client.doRequest(ctx, strings.ToUpper(httpMethod), public, endpoint, params, result)
+}
+
+// cleanEndpoint resolves the dot segments of an endpoint relative to the api
+// version, with or without the version prefix. Escapes, queries and fragments
+// are rejected, as the server would decode the path after its capability was
+// checked, e.g. "account/crypto/%77ithdraw".
+func cleanEndpoint(endpoint string) (string, error) {
+	if strings.ContainsAny(endpoint, "%?#") {
+		return "", fmt.Errorf("CryptomarketSDKError: invalid endpoint: %v", endpoint)
+	}
+	cleaned := strings.TrimPrefix(strings.TrimPrefix(endpoint, "/"), strings.TrimPrefix(apiVersion, "/"))
+	cleaned = path.Clean(cleaned)
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "/") || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("CryptomarketSDKError: invalid endpoint: %v", endpoint)
+	}
+	return cleaned, nil
+}
+
 func (client *Client) publicGet(ctx context.Context, endpoint string, params map[string]interface{}, model interface{}) error {
 	return client.doRequest(ctx, methodGet, publicCall, endpoint, params, model)
 }
@@ -67,24 +148,66 @@ func (client *Client) delete(ctx context.Context, endpoint string, params map[st
 }
 
 func (client *Client) doRequest(ctx context.Context, method string, public bool, endpoint string, params map[string]interface{}, model interface{}) error {
-	data, err := client.hclient.doRequest(ctx, method, endpoint, params, public)
-	if err != nil {
+	// checked here to spare the middlewares the requests rejected anyway, and
+	// again by send on the request left by them.
+	if err := auth.Check(method+" "+endpoint, requiredCapability(method, endpoint), client.capabilities); err != nil {
 		return err
 	}
-	return client.handleResponseData(data, model)
+	data, err := client.handler(ctx, &Request{
+		Method:   method,
+		Endpoint: endpoint,
+		Params:   params,
+		Public:   public,
+		Header:   make(http.Header),
+	})
+	if err == nil {
+		err = client.handleResponseData(data, model)
+	}
+	if client.audit != nil && method != methodGet {
+		auditErr := client.audit.Append(method+" "+endpoint, params, audit.ResultID(data), err)
+		if auditErr != nil {
+			client.logger.Error("audit record not written", "method", method, "endpoint", endpoint, "error", auditErr)
+		}
+		if err == nil {
+			err = auditErr
+		}
+	}
+	return err
+}
+
+// send is the handler wrapped by the middlewares. It checks the capability
+// of the request as left by them, with its endpoint cleaned, and sends it.
+func (client *Client) send(ctx context.Context, req *Request) ([]byte, error) {
+	endpoint, err := cleanEndpoint(req.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	req.Endpoint = endpoint
+	if err := auth.Check(req.Method+" "+endpoint, requiredCapability(req.Method, endpoint), client.capabilities); err != nil {
+		return nil, err
+	}
+	return client.hclient.doRequest(ctx, req)
 }
 
 func (client *Client) handleResponseData(data []byte, model interface{}) error {
+	if err := apiError(data); err != nil {
+		return err
+	}
+	err := json.Unmarshal(data, model)
+	if err != nil {
+		return errors.New("CryptomarketSDKError: Failed to parse response data: " + err.Error())
+	}
+	return nil
+}
+
+// apiError returns the error of the exchange in a response, if any.
+func apiError(data []byte) error {
 	errorResponse := models.ErrorMetadata{}
 	json.Unmarshal(data, &errorResponse)
 	serverError := errorResponse.Error
 	if serverError != nil { // is a real error
 		return fmt.Errorf("CryptomarketAPIError: (code=%v) %v. %v", serverError.Code, serverError.Message, serverError.Description)
 	}
-	err := json.Unmarshal(data, model)
-	if err != nil {
-		return errors.New("CryptomarketSDKError: Failed to parse response data: " + err.Error())
-	}
 	return nil
 }
 
@@ -252,6 +375,8 @@ func (client *Client) GetOrderbook(ctx context.Context, arguments ...args.Argume
 //
 // An Order Book is an electronic list of buy and sell orders for a specific symbol, structured by price level.
 //
+// The same info can be computed from an already known orderbook with orderbook.MarketDepth.
+//
 // https://api.exchange.cryptomarket.com/#order-book
 //
 // Arguments:
@@ -603,7 +728,7 @@ func (client *Client) GetLast10UsedCryptoAddresses(ctx context.Context, argument
 //  PaymentID(string) // Optional.
 //  IncludeFee(bool)  // Optional. If true then the total spent amount includes fees. Default false
 //  AutoCommit(bool)  // Optional. If false then you should commit or rollback transaction in an hour. Used in two phase commit schema. Default true
-func (client *Client) withdrawCrypto(ctx context.Context, arguments ...args.Argument) (result *models.Transaction, err error) {
+func (client *Client) WithdrawCrypto(ctx context.Context, arguments ...args.Argument) (result *models.Transaction, err error) {
 	params, err := args.BuildParams(arguments, "currency", "amount", "address")
 	if err != nil {
 		return
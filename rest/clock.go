@@ -0,0 +1,60 @@
+package rest
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// skewAlpha is the weight of a new sample in the offset estimate. Samples
+// have the one second resolution of the Date header, so they are smoothed.
+const skewAlpha = 0.25
+
+// skewEstimator estimates the offset of the server clock from the local one.
+// Only the Date header of the responses is used: the errors of the exchange
+// for a stale timestamp carry no server time, but their responses have a Date
+// header, observed like the one of any other response.
+type skewEstimator struct {
+	mutex   sync.Mutex
+	offset  time.Duration
+	samples int64
+}
+
+// observe adds the server time of a response, sent and received at the
+// given local times.
+func (estimator *skewEstimator) observe(header http.Header, sent, received time.Time) {
+	date, err := http.ParseTime(header.Get("Date"))
+	if err != nil {
+		return
+	}
+	// the Date header is truncated to the second, take the middle of it,
+	// against the middle of the round trip
+	server := date.Add(500 * time.Millisecond)
+	local := sent.Add(received.Sub(sent) / 2)
+	sample := server.Sub(local)
+
+	estimator.mutex.Lock()
+	defer estimator.mutex.Unlock()
+	if estimator.samples == 0 {
+		estimator.offset = sample
+	} else {
+		estimator.offset += time.Duration(skewAlpha * float64(sample-estimator.offset))
+	}
+	estimator.samples++
+}
+
+func (estimator *skewEstimator) get() time.Duration {
+	estimator.mutex.Lock()
+	defer estimator.mutex.Unlock()
+	return estimator.offset
+}
+
+// ClockOffset returns the offset of the server clock from the local clock,
+// added to the timestamps of the signed requests. Zero unless the client was
+// built WithSkewCompensation, and until a response is received.
+func (client *Client) ClockOffset() time.Duration {
+	if client.hclient.skew == nil {
+		return 0
+	}
+	return client.hclient.skew.get()
+}
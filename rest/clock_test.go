@@ -0,0 +1,111 @@
+package rest
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cryptomarket/cryptomarket-go/auth"
+)
+
+// signedTimestamp returns the timestamp of an HS256 Authorization header.
+func signedTimestamp(t *testing.T, header string) string {
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(header, "HS256 "))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(string(decoded), ":")[1]
+}
+
+func TestClockSkew(t *testing.T) {
+	local := time.Date(2021, 1, 20, 20, 0, 0, 0, time.UTC)
+	server := local.Add(90 * time.Second)
+	headers := make(chan string, 3)
+	withFakeServer(t, func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header.Get("Authorization")
+		w.Header().Set("Date", server.Format(http.TimeFormat))
+		w.Write([]byte(`[]`))
+	})
+	clock := func() time.Time { return local }
+
+	// without compensation, the local clock signs
+	client := NewClientWithSigner(auth.HS256("key", "secret"), WithClock(clock))
+	if _, err := client.GetTradingBalance(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if ts := signedTimestamp(t, <-headers); ts != "1611172800" {
+		t.Fatalf("wrong timestamp: %v", ts)
+	}
+	if client.ClockOffset() != 0 {
+		t.Fatalf("unexpected offset: %v", client.ClockOffset())
+	}
+
+	// with compensation, the offset is learnt from the first response
+	client = NewClientWithSigner(auth.HS256("key", "secret"), WithClock(clock), WithSkewCompensation())
+	if _, err := client.GetCurrencies(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	<-headers
+	if offset := client.ClockOffset(); offset != 90*time.Second+500*time.Millisecond {
+		t.Fatalf("wrong offset: %v", offset)
+	}
+	if _, err := client.GetTradingBalance(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if ts := signedTimestamp(t, <-headers); ts != "1611172890" {
+		t.Fatalf("wrong compensated timestamp: %v", ts)
+	}
+}
+
+func TestClockSkewFromRejectedRequest(t *testing.T) {
+	local := time.Date(2021, 1, 20, 20, 0, 0, 0, time.UTC)
+	server := local.Add(-time.Hour)
+	headers := make(chan string, 2)
+	requests := 0
+	withFakeServer(t, func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header.Get("Authorization")
+		w.Header().Set("Date", server.Format(http.TimeFormat))
+		requests++
+		if requests == 1 {
+			// the first request is rejected for its timestamp
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":{"code":1002,"message":"Authorization is required or has been failed"}}`))
+			return
+		}
+		w.Write([]byte(`[]`))
+	})
+	client := NewClientWithSigner(auth.HS256("key", "secret"), WithClock(func() time.Time { return local }), WithSkewCompensation())
+	if _, err := client.GetTradingBalance(context.Background()); err == nil {
+		t.Fatal("expected an authorization error")
+	}
+	<-headers
+	if _, err := client.GetTradingBalance(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if ts := signedTimestamp(t, <-headers); ts != "1611169200" {
+		t.Fatalf("the rejected response should set the offset: %v", ts)
+	}
+}
+
+func TestSkewEstimator(t *testing.T) {
+	estimator := &skewEstimator{}
+	sent := time.Date(2021, 1, 20, 20, 0, 0, 0, time.UTC)
+	header := http.Header{}
+	header.Set("Date", sent.Add(10*time.Second).Format(http.TimeFormat))
+	estimator.observe(header, sent, sent.Add(time.Second))
+	if offset := estimator.get(); offset != 10*time.Second {
+		t.Fatalf("wrong first offset: %v", offset)
+	}
+	header.Set("Date", sent.Add(14*time.Second).Format(http.TimeFormat))
+	estimator.observe(header, sent, sent.Add(time.Second))
+	if offset := estimator.get(); offset != 11*time.Second {
+		t.Fatalf("samples should be smoothed: %v", offset)
+	}
+	estimator.observe(http.Header{}, sent, sent)
+	if estimator.samples != 2 {
+		t.Fatal("responses without a date should be ignored")
+	}
+}
@@ -28,3 +28,14 @@ const (
 	endpointAccountTranserInternal = "account/transfer/internal"
 	endpointTransactionHistory     = "account/transactions"
 )
+
+// endpoints are all the endpoints, for the ones that take ids in their path
+// to be recognized.
+var endpoints = []string{
+	endpointCurrency, endpointSymbol, endpointTrade, endpointOrderbook, endpointCandle, endpointTicker,
+	endpointTradingBalance, endpointOrder, endpointTradingFee,
+	endpointOrderHistory, endpointTradeHistory,
+	endpointAccountBalance, endpointCryptoAdress, endpointCryptoAdresses, endpointUsedAddressed,
+	endpointWithdrawCrypto, endpointTransferConvert, endpointEstimateWithdraw, endpointCryptoAddressIsMine,
+	endpointAccountTranser, endpointAccountTranserInternal, endpointTransactionHistory,
+}
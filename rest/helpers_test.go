@@ -1,10 +1,10 @@
 package rest
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io/ioutil"
 
+	"github.com/cryptomarket/cryptomarket-go/credentials"
 	"github.com/cryptomarket/cryptomarket-go/models"
 )
 
@@ -13,13 +13,14 @@ type APIKeys struct {
 	APISecret string `json:"apiSecret"`
 }
 
+// LoadKeys loads the keys of the tests from the environment or the default
+// profile file, see the credentials package.
 func LoadKeys() (apiKeys APIKeys) {
-	data, err := ioutil.ReadFile("/home/ismael/cryptomarket/apis/keys.json")
+	keys, err := credentials.Default("").Retrieve(context.Background())
 	if err != nil {
 		fmt.Print(err)
 	}
-	err = json.Unmarshal(data, &apiKeys)
-	return
+	return APIKeys{APIKey: keys.APIKey, APISecret: keys.APISecret}
 }
 
 func checkNoNil(field interface{}, name string) (err error) {
@@ -2,19 +2,19 @@ package rest
 
 import (
 	"context"
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/base64"
-	"encoding/hex"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/cryptomarket/cryptomarket-go/args"
+	"github.com/cryptomarket/cryptomarket-go/auth"
+	"github.com/cryptomarket/cryptomarket-go/logging"
 )
 
 var (
@@ -26,23 +26,47 @@ var (
 // accepts Get, Post, Put and Delete functions, all with parameters and return
 // the response bytes
 type httpclient struct {
-	client    *http.Client
-	apiKey    string
-	apiSecret string
+	client *http.Client
+	signer *atomic.Value // of signerBox, swapped by SetSigner
+	clock  func() time.Time
+	skew   *skewEstimator // nil without skew compensation
+	logger logging.Logger
 }
 
 // New creates a new httpclient
-func newHTTPClient(apiKey, apiSecret string) httpclient {
-	return httpclient{
-		client:    &http.Client{},
-		apiKey:    apiKey,
-		apiSecret: apiSecret,
+func newHTTPClient(signer auth.Signer) httpclient {
+	hclient := httpclient{
+		client: &http.Client{},
+		signer: &atomic.Value{},
+		clock:  time.Now,
+		logger: logging.Discard,
 	}
+	hclient.setSigner(signer)
+	return hclient
 }
 
-func (hclient httpclient) doRequest(cxt context.Context, method, endpoint string, params map[string]interface{}, public bool) (result []byte, err error) {
+// signerBox boxes the signers, as an atomic.Value needs values of a single concrete type.
+type signerBox struct {
+	signer auth.Signer
+}
+
+func (hclient httpclient) setSigner(signer auth.Signer) {
+	hclient.signer.Store(signerBox{signer})
+}
+
+// now returns the time of the server, as far as known.
+func (hclient httpclient) now() time.Time {
+	now := hclient.clock()
+	if hclient.skew != nil {
+		now = now.Add(hclient.skew.get())
+	}
+	return now
+}
+
+func (hclient httpclient) doRequest(cxt context.Context, request *Request) (result []byte, err error) {
+	method, endpoint, public := request.Method, request.Endpoint, request.Public
 	// build query
-	rawQuery := buildQuery(params)
+	rawQuery := buildQuery(request.Params)
 	// build request
 	var req *http.Request
 	if method == methodGet {
@@ -55,19 +79,34 @@ func (hclient httpclient) doRequest(cxt context.Context, method, endpoint string
 		return nil, errors.New("CryptomarketSDKError: Can't build the request: " + err.Error())
 	}
 
+	for key, values := range request.Header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
 	req.Header.Add("User-Agent", "cryptomarket/go")
 	req.Header.Add("Content-type", "application/x-www-form-urlencoded")
 	// add auth header if is not a public call
 	if !public {
-		req.Header.Add("Authorization", hclient.buildCredential(method, endpoint, rawQuery))
+		credential, err := hclient.buildCredential(cxt, method, endpoint, rawQuery)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Add("Authorization", credential)
 	}
 
 	// make request
+	sent := hclient.clock()
 	resp, err := hclient.client.Do(req)
 	if err != nil {
 		return nil, errors.New("CryptomarketSDKError: Can't make the request: " + err.Error())
 	}
 	defer resp.Body.Close()
+	request.StatusCode = resp.StatusCode
+	if hclient.skew != nil {
+		hclient.skew.observe(resp.Header, sent, hclient.clock())
+		hclient.logger.Debug("clock offset", "offset", hclient.skew.get())
+	}
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return nil, errors.New("CryptomarketSDKError: Can't read the response body: " + err.Error())
@@ -75,19 +114,14 @@ func (hclient httpclient) doRequest(cxt context.Context, method, endpoint string
 	return body, nil
 }
 
-func (hclient httpclient) buildCredential(httpMethod, method, query string) string {
-	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
-	msg := httpMethod + timestamp + apiVersion + method
-	if len(query) != 0 {
-		if httpMethod == methodGet {
-			msg += "?"
-		}
-		msg += query
-	}
-	h := hmac.New(sha256.New, []byte(hclient.apiSecret))
-	h.Write([]byte(msg))
-	signature := hex.EncodeToString(h.Sum(nil))
-	return "HS256 " + base64.StdEncoding.EncodeToString([]byte(hclient.apiKey+":"+timestamp+":"+signature))
+func (hclient httpclient) buildCredential(ctx context.Context, httpMethod, method, query string) (string, error) {
+	signer := hclient.signer.Load().(signerBox).signer
+	return signer.Authorization(ctx, auth.Request{
+		Method:    httpMethod,
+		Path:      apiVersion + method,
+		Query:     query,
+		Timestamp: hclient.now(),
+	})
 }
 
 func buildQuery(params map[string]interface{}) string {
@@ -101,6 +135,8 @@ func buildQuery(params map[string]interface{}) string {
 			query.Add(key, v)
 		case int:
 			query.Add(key, strconv.Itoa(v))
+		case int64:
+			query.Add(key, strconv.FormatInt(v, 10))
 		case args.IdentifyByType:
 			query.Add(key, string(v))
 		case args.MarginType:
@@ -117,6 +153,9 @@ func buildQuery(params map[string]interface{}) string {
 			query.Add(key, string(v))
 		case args.TimeInForceType:
 			query.Add(key, string(v))
+		default:
+			// params of raw requests
+			query.Add(key, fmt.Sprint(v))
 		}
 	}
 	return query.Encode()
@@ -0,0 +1,91 @@
+package rest
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/cryptomarket/cryptomarket-go/auth"
+)
+
+// withFakeServer points the clients to a test server during a test.
+func withFakeServer(t *testing.T, handler http.HandlerFunc) {
+	server := httptest.NewServer(handler)
+	url := apiURL
+	apiURL = server.URL
+	t.Cleanup(func() {
+		apiURL = url
+		server.Close()
+	})
+}
+
+func TestClientWithSigner(t *testing.T) {
+	headers := make(chan string, 2)
+	withFakeServer(t, func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header.Get("Authorization")
+		w.Write([]byte(`[]`))
+	})
+	client := NewClientWithSigner(auth.Basic("key", "secret"))
+	if _, err := client.GetTradingBalance(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if header := <-headers; header != "Basic a2V5OnNlY3JldA==" {
+		t.Fatalf("wrong authorization: %v", header)
+	}
+	if _, err := client.GetCurrencies(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if header := <-headers; header != "" {
+		t.Fatalf("public requests should not be signed: %v", header)
+	}
+}
+
+func TestSetCredentials(t *testing.T) {
+	const requests = 50
+	headers := make(chan string, requests)
+	withFakeServer(t, func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header.Get("Authorization")
+		w.Write([]byte(`[]`))
+	})
+	signers := []auth.Signer{auth.Basic("old", "first"), auth.Basic("new", "second")}
+	expected := map[string]bool{
+		"Basic " + base64.StdEncoding.EncodeToString([]byte("old:first")):  true,
+		"Basic " + base64.StdEncoding.EncodeToString([]byte("new:second")): true,
+	}
+	client := NewClientWithSigner(signers[0])
+	done := make(chan bool)
+	go func() {
+		// rotations between the two signers race with requests
+		for i := 0; i < 1000; i++ {
+			client.SetSigner(signers[i%2])
+		}
+		close(done)
+	}()
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.GetTradingBalance(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	<-done
+	for i := 0; i < requests; i++ {
+		if header := <-headers; !expected[header] {
+			t.Errorf("authorization of neither signer: %v", header)
+		}
+	}
+	client.SetSigner(auth.Basic("new", "secret"))
+	if _, err := client.GetTradingBalance(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if header := <-headers; header != "Basic bmV3OnNlY3JldA==" {
+		t.Fatalf("wrong authorization after the rotation: %v", header)
+	}
+}
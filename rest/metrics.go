@@ -0,0 +1,59 @@
+package rest
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cryptomarket/cryptomarket-go/metrics"
+)
+
+// WithMetrics records the count and the latency of the requests of the
+// client, by endpoint, status and http status code.
+func WithMetrics(recorder metrics.Recorder) Option {
+	return WithMiddleware(Metrics(recorder))
+}
+
+// Metrics is a middleware recording the count and the latency of the
+// requests, by endpoint, status and http status code. The code is empty for
+// requests without a response.
+func Metrics(recorder metrics.Recorder) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) ([]byte, error) {
+			start := time.Now()
+			data, err := next(ctx, req)
+			endpoint := endpointLabel(req.Endpoint)
+			recorder.Observe(metrics.RESTRequestDuration, metrics.Labels{"method": req.Method, "endpoint": endpoint}, time.Since(start).Seconds())
+			status := "ok"
+			if err != nil {
+				status = "error"
+			} else if apiError(data) != nil {
+				status = "api_error"
+			}
+			code := ""
+			if req.StatusCode != 0 {
+				code = strconv.Itoa(req.StatusCode)
+			}
+			recorder.Add(metrics.RESTRequests, metrics.Labels{"method": req.Method, "endpoint": endpoint, "status": status, "code": code}, 1)
+			return data, err
+		}
+	}
+}
+
+// endpointLabel replaces the id following an endpoint in a path, e.g. a
+// symbol, a currency or an order id, with ":id", to keep the labels few.
+func endpointLabel(path string) string {
+	base := ""
+	for _, endpoint := range endpoints {
+		if (path == endpoint || strings.HasPrefix(path, endpoint+"/")) && len(endpoint) > len(base) {
+			base = endpoint
+		}
+	}
+	if base == "" || path == base {
+		return path
+	}
+	rest := strings.SplitN(path[len(base)+1:], "/", 2)
+	rest[0] = ":id"
+	return base + "/" + strings.Join(rest, "/")
+}
@@ -0,0 +1,58 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/cryptomarket/cryptomarket-go/args"
+	"github.com/cryptomarket/cryptomarket-go/metrics"
+)
+
+func TestMetrics(t *testing.T) {
+	withFakeServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == methodDelete {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"code":20002,"message":"Order not found"}}`))
+			return
+		}
+		w.Write([]byte(`{"symbol":"EOSETH"}`))
+	})
+	registry := metrics.NewRegistry()
+	client := NewClient("key", "secret", WithMetrics(registry))
+	ctx := context.Background()
+	client.GetTicker(ctx, args.Symbol("EOSETH"))
+	client.GetTicker(ctx, args.Symbol("ETHBTC"))
+	client.CancelOrder(ctx, args.ClientOrderID("d8574207d9e3b16a4a5511753eeef175"))
+
+	cases := []struct {
+		labels   metrics.Labels
+		expected float64
+	}{
+		{metrics.Labels{"method": methodGet, "endpoint": "public/ticker/:id", "status": "ok", "code": "200"}, 2},
+		{metrics.Labels{"method": methodDelete, "endpoint": "order/:id", "status": "api_error", "code": "400"}, 1},
+	}
+	for _, c := range cases {
+		if value := registry.Value(metrics.RESTRequests, c.labels); value != c.expected {
+			t.Errorf("%v: got %v, expected %v", c.labels, value, c.expected)
+		}
+	}
+	if registry.Value(metrics.RESTRequestDuration, metrics.Labels{"method": methodGet, "endpoint": "public/ticker/:id"}) != 2 {
+		t.Error("missing latencies")
+	}
+}
+
+func TestEndpointLabel(t *testing.T) {
+	cases := map[string]string{
+		endpointTradingBalance:                     endpointTradingBalance,
+		endpointOrder + "/abc":                     "order/:id",
+		endpointOrderHistory + "/840450210/trades": "history/order/:id/trades",
+		endpointAccountTranserInternal:             endpointAccountTranserInternal,
+		endpointCryptoAdresses + "/ETH":            "account/crypto/addresses/:id",
+	}
+	for path, expected := range cases {
+		if label := endpointLabel(path); label != expected {
+			t.Errorf("%v: got %v, expected %v", path, label, expected)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cryptomarket/cryptomarket-go/logging"
+)
+
+// Request describes a request of the client as it flows through the
+// middlewares.
+type Request struct {
+	Method   string // http method
+	Endpoint string // relative to the api version, e.g. "public/ticker"
+	Params   map[string]interface{}
+	Public   bool        // not signed
+	Header   http.Header // extra headers to send
+	// StatusCode is the http status of the response, set by the client once
+	// received, 0 if there was none
+	StatusCode int
+}
+
+// Handler sends a request, returning the body of the response.
+type Handler func(ctx context.Context, req *Request) ([]byte, error)
+
+// Middleware wraps a handler. It can change the request, answer it without
+// calling next, e.g. from a cache, or inspect the response.
+type Middleware func(next Handler) Handler
+
+// WithMiddleware adds middlewares to the client. Every request flows through
+// them, the first one being the outermost. Requests rejected by the
+// capabilities of the client don't reach them, and the capabilities are
+// checked again on the requests as changed by them.
+func WithMiddleware(middlewares ...Middleware) Option {
+	return func(client *Client) {
+		client.middlewares = append(client.middlewares, middlewares...)
+	}
+}
+
+// chain wraps handler with the middlewares.
+func chain(middlewares []Middleware, handler Handler) Handler {
+	for idx := len(middlewares) - 1; idx >= 0; idx-- {
+		handler = middlewares[idx](handler)
+	}
+	return handler
+}
+
+// Timing calls observe with the duration of every request and its error,
+// either of the transport or of the exchange.
+func Timing(observe func(req Request, duration time.Duration, err error)) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) ([]byte, error) {
+			start := time.Now()
+			data, err := next(ctx, req)
+			observe(*req, time.Since(start), responseError(data, err))
+			return data, err
+		}
+	}
+}
+
+// Logging logs every request to logger, at debug level, or at warn level if
+// it fails. Param values are not logged, as they may be sensitive.
+func Logging(logger logging.Logger) Middleware {
+	return Timing(func(req Request, duration time.Duration, err error) {
+		keys := make([]string, 0, len(req.Params))
+		for key := range req.Params {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		keyvals := []interface{}{
+			"method", req.Method,
+			"endpoint", req.Endpoint,
+			"public", req.Public,
+			"params", strings.Join(keys, ","),
+			"duration", duration,
+		}
+		if err != nil {
+			logger.Warn("request failed", append(keyvals, "error", err)...)
+			return
+		}
+		logger.Debug("request", keyvals...)
+	})
+}
+
+// responseError returns err, or the error of the exchange in data.
+func responseError(data []byte, err error) error {
+	if err != nil {
+		return err
+	}
+	return apiError(data)
+}
@@ -0,0 +1,88 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cryptomarket/cryptomarket-go/auth"
+	"github.com/cryptomarket/cryptomarket-go/logging"
+)
+
+func TestMiddleware(t *testing.T) {
+	headers := make(chan string, 1)
+	withFakeServer(t, func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header.Get("X-Request-Id")
+		w.Write([]byte(`{"error":{"code":20001,"message":"Insufficient funds"}}`))
+	})
+	calls := make([]string, 0)
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, req *Request) ([]byte, error) {
+				calls = append(calls, name)
+				req.Header.Set("X-Request-Id", "abc")
+				return next(ctx, req)
+			}
+		}
+	}
+	var timed error
+	var logs bytes.Buffer
+	client := NewClient("key", "secret",
+		WithMiddleware(trace("outer"), trace("inner")),
+		WithMiddleware(
+			Timing(func(req Request, duration time.Duration, err error) { timed = err }),
+			Logging(logging.New(&logs, logging.LevelDebug)),
+		),
+	)
+	if _, err := client.GetTradingBalance(context.Background()); err == nil {
+		t.Fatal("expected an api error")
+	}
+	if strings.Join(calls, ",") != "outer,inner" || <-headers != "abc" {
+		t.Fatalf("wrong chain: %v", calls)
+	}
+	if timed == nil || !strings.Contains(logs.String(), "level=WARN msg=\"request failed\" method=GET endpoint=trading/balance public=false") {
+		t.Fatalf("wrong observations: %v, %q", timed, logs.String())
+	}
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+	withFakeServer(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the request reached the server")
+	})
+	chaos := func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) ([]byte, error) {
+			return nil, errors.New("injected")
+		}
+	}
+	client := NewClient("key", "secret", WithMiddleware(chaos))
+	if _, err := client.GetTradingBalance(context.Background()); err == nil || err.Error() != "injected" {
+		t.Fatalf("expected the injected error, got %v", err)
+	}
+}
+
+func TestMiddlewareRewriteIsChecked(t *testing.T) {
+	withFakeServer(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("the request reached the server: %v", r.URL)
+	})
+	for _, endpoint := range []string{endpointWithdrawCrypto, "public/../" + endpointWithdrawCrypto} {
+		rewrite := func(endpoint string) Middleware {
+			return func(next Handler) Handler {
+				return func(ctx context.Context, req *Request) ([]byte, error) {
+					req.Method = methodPost
+					req.Endpoint = endpoint
+					return next(ctx, req)
+				}
+			}
+		}
+		client := NewClient("key", "secret", WithCapabilities(auth.ReadOnly), WithMiddleware(rewrite(endpoint)))
+		_, err := client.GetTradingBalance(context.Background())
+		var capabilityErr *auth.CapabilityError
+		if !errors.As(err, &capabilityErr) || capabilityErr.Required != auth.Withdraw {
+			t.Fatalf("%v: expected a withdraw capability error, got %v", endpoint, err)
+		}
+	}
+}
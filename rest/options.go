@@ -0,0 +1,49 @@
+package rest
+
+import (
+	"time"
+
+	"github.com/cryptomarket/cryptomarket-go/audit"
+	"github.com/cryptomarket/cryptomarket-go/logging"
+)
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithClock sets the clock of the timestamps of the signed requests.
+// Default is time.Now.
+func WithClock(clock func() time.Time) Option {
+	return func(client *Client) {
+		client.hclient.clock = clock
+	}
+}
+
+// WithSkewCompensation makes the client learn the offset of the server clock
+// from the Date header of the responses, failed ones included, and sign requests with the server
+// time, so a drifting host clock does not fail the authentication. The
+// measured offset is given by ClockOffset.
+func WithSkewCompensation() Option {
+	return func(client *Client) {
+		client.hclient.skew = &skewEstimator{}
+	}
+}
+
+// WithAudit records every request changing the account, such as orders,
+// cancels, transfers, conversions and withdrawals, along with the reply of
+// the exchange, in log. A call that succeeds but can't be recorded returns
+// the error of the log.
+func WithAudit(log *audit.Log) Option {
+	return func(client *Client) {
+		client.audit = log
+	}
+}
+
+// WithLogger sets the logger of the client, which logs signer changes,
+// clock offsets and audit failures. Requests are logged by the Logging
+// middleware. Default is logging.Discard.
+func WithLogger(logger logging.Logger) Option {
+	return func(client *Client) {
+		client.logger = logger
+		client.hclient.logger = logger
+	}
+}
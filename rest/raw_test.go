@@ -0,0 +1,88 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cryptomarket/cryptomarket-go/auth"
+)
+
+func TestDo(t *testing.T) {
+	withFakeServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == apiVersion+"public/futures/EOSETH" && r.URL.Query().Get("limit") == "5" && r.Header.Get("Authorization") == "":
+			w.Write([]byte(`{"symbol":"EOSETH","rate":"0.01"}`))
+		case r.URL.Path == apiVersion+"margin/order" && r.Method == methodPost && r.Header.Get("Authorization") != "":
+			r.ParseForm()
+			if r.PostForm.Get("postOnly") != "true" {
+				t.Errorf("wrong form: %v", r.PostForm)
+			}
+			w.Write([]byte(`{"error":{"code":20001,"message":"Insufficient funds"}}`))
+		default:
+			t.Errorf("unexpected request %v %v", r.Method, r.URL)
+		}
+	})
+	client := NewClient("key", "secret")
+	var result struct {
+		Symbol string
+		Rate   string
+	}
+	if err := client.Do(context.Background(), "get", "/api/2/public/futures/EOSETH", map[string]interface{}{"limit": 5}, true, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Rate != "0.01" {
+		t.Fatalf("wrong result: %+v", result)
+	}
+	err := client.Do(context.Background(), methodPost, "margin/order", map[string]interface{}{"postOnly": true}, false, &result)
+	if err == nil {
+		t.Fatal("expected an api error")
+	}
+}
+
+func TestDoCleansEndpoint(t *testing.T) {
+	requests := 0
+	withFakeServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != apiVersion+"public/ticker/EOSETH" {
+			t.Errorf("unexpected request %v %v", r.Method, r.URL)
+		}
+		w.Write([]byte(`{}`))
+	})
+	client := NewClient("key", "secret", WithCapabilities(auth.MarketData))
+	var result map[string]interface{}
+	if err := client.Do(context.Background(), methodGet, "public/./ticker//EOSETH", nil, true, &result); err != nil {
+		t.Fatal(err)
+	}
+	for _, endpoint := range []string{
+		"public/../account/crypto/withdraw",
+		"./account/crypto/withdraw",
+		"public/ticker/../../account/crypto/withdraw/",
+	} {
+		err := client.Do(context.Background(), methodPost, endpoint, nil, true, &result)
+		var capabilityErr *auth.CapabilityError
+		if !errors.As(err, &capabilityErr) || capabilityErr.Required != auth.Withdraw {
+			t.Errorf("%v: expected a withdraw capability error, got %v", endpoint, err)
+		}
+	}
+	for _, endpoint := range []string{"..", "../api/3/account", "public/../../x", "//account/balance", "", "/",
+		"account/crypto/%77ithdraw", "account/crypto/withdraw%2F", "public/%2E%2E/account/crypto/withdraw",
+		"account/crypto/transfer-convert?/../withdraw", "account/crypto/transfer-convert#/../../withdraw"} {
+		if err := client.Do(context.Background(), methodGet, endpoint, nil, true, &result); err == nil {
+			t.Errorf("%v: expected an invalid endpoint error", endpoint)
+		}
+	}
+	// escaped endpoints would be checked against another capability than the one decoded by the server
+	trader := NewClient("key", "secret", WithCapabilities(auth.ReadOnly|auth.Trade|auth.Transfer))
+	for _, endpoint := range []string{"account/crypto/%77ithdraw", "account/crypto/withdraw%2F", "public/%2E%2E/account/crypto/withdraw"} {
+		err := trader.Do(context.Background(), methodPost, endpoint, map[string]interface{}{"currency": "ETH"}, false, &result)
+		if err == nil || !strings.Contains(err.Error(), "invalid endpoint") {
+			t.Errorf("%v: expected an invalid endpoint error, got %v", endpoint, err)
+		}
+	}
+	if requests != 1 {
+		t.Fatalf("rejected requests reached the server: %v requests", requests)
+	}
+}
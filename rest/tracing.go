@@ -0,0 +1,47 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/cryptomarket/cryptomarket-go/audit"
+	"github.com/cryptomarket/cryptomarket-go/models"
+	"github.com/cryptomarket/cryptomarket-go/tracing"
+)
+
+// WithTracer traces every request of the client with tracer, see Tracing.
+func WithTracer(tracer tracing.Tracer) Option {
+	return WithMiddleware(Tracing(tracer))
+}
+
+// Tracing is a middleware starting a span per request, as a child of the
+// span in the context of the call. The span carries the symbol, the client
+// order id and the result id of the request, and the error code of the
+// exchange if it fails. The context passed down holds the span, for the
+// following middlewares to propagate it.
+func Tracing(tracer tracing.Tracer) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) ([]byte, error) {
+			endpoint := endpointLabel(req.Endpoint)
+			ctx, span := tracer.Start(ctx, "cryptomarket.rest "+req.Method+" "+endpoint)
+			defer span.End()
+			span.SetAttribute(tracing.AttributeMethod, req.Method)
+			span.SetAttribute(tracing.AttributeEndpoint, endpoint)
+			tracing.SetParams(span, req.Params)
+			data, err := next(ctx, req)
+			if err != nil {
+				span.RecordError(err)
+				return data, err
+			}
+			errorResponse := models.ErrorMetadata{}
+			json.Unmarshal(data, &errorResponse)
+			if errorResponse.Error != nil {
+				span.SetAttribute(tracing.AttributeErrorCode, errorResponse.Error.Code)
+				span.RecordError(apiError(data))
+			} else if id := audit.ResultID(data); id != "" {
+				span.SetAttribute(tracing.AttributeResultID, id)
+			}
+			return data, err
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/cryptomarket/cryptomarket-go/args"
+	"github.com/cryptomarket/cryptomarket-go/tracing"
+)
+
+type spanKey struct{}
+
+type fakeSpan struct {
+	name       string
+	parent     *fakeSpan
+	attributes map[string]interface{}
+	err        error
+	ended      bool
+}
+
+func (span *fakeSpan) SetAttribute(key string, value interface{}) { span.attributes[key] = value }
+func (span *fakeSpan) RecordError(err error)                      { span.err = err }
+func (span *fakeSpan) End()                                       { span.ended = true }
+
+type fakeTracer struct {
+	mutex sync.Mutex
+	spans []*fakeSpan
+}
+
+func (tracer *fakeTracer) Start(ctx context.Context, name string) (context.Context, tracing.Span) {
+	parent, _ := ctx.Value(spanKey{}).(*fakeSpan)
+	span := &fakeSpan{name: name, parent: parent, attributes: make(map[string]interface{})}
+	tracer.mutex.Lock()
+	tracer.spans = append(tracer.spans, span)
+	tracer.mutex.Unlock()
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func TestTracing(t *testing.T) {
+	withFakeServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == methodDelete {
+			w.Write([]byte(`{"error":{"code":20002,"message":"Order not found"}}`))
+			return
+		}
+		w.Write([]byte(`{"id":840450210,"clientOrderId":"abc","symbol":"EOSETH"}`))
+	})
+	tracer := &fakeTracer{}
+	client := NewClient("key", "secret", WithTracer(tracer))
+	parent := &fakeSpan{name: "handler"}
+	ctx := context.WithValue(context.Background(), spanKey{}, parent)
+	client.CreateOrder(ctx, args.Symbol("EOSETH"), args.Side(args.SideTypeSell), args.Quantity("0.01"), args.ClientOrderID("abc"))
+	client.CancelOrder(ctx, args.ClientOrderID("xyz"))
+
+	if len(tracer.spans) != 2 {
+		t.Fatalf("expected 2 spans, got %v", len(tracer.spans))
+	}
+	created, canceled := tracer.spans[0], tracer.spans[1]
+	if created.name != "cryptomarket.rest PUT order/:id" || created.parent != parent || !created.ended {
+		t.Fatalf("wrong span: %+v", created)
+	}
+	if created.attributes[tracing.AttributeSymbol] != "EOSETH" || created.attributes[tracing.AttributeResultID] != "abc" {
+		t.Fatalf("wrong attributes: %v", created.attributes)
+	}
+	if canceled.attributes[tracing.AttributeErrorCode] != 20002 || canceled.err == nil || canceled.attributes[tracing.AttributeEndpoint] != "order/:id" {
+		t.Fatalf("wrong failed span: %+v", canceled)
+	}
+}
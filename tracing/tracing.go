@@ -0,0 +1,64 @@
+// Package tracing defines the hooks the clients of the sdk use to trace
+// their calls.
+//
+// A Tracer starts a span per rest request and per websocket request, as a
+// child of the span in the context of the call, if any. Adapting a tracing
+// library, e.g. OpenTelemetry, takes a Tracer delegating to the library and
+// a Span wrapping its spans.
+package tracing
+
+import "context"
+
+// Tracer starts spans.
+type Tracer interface {
+	// Start starts a span as a child of the span in ctx, if any, returning
+	// a context holding the new span.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is an operation being traced.
+type Span interface {
+	// SetAttribute annotates the span.
+	SetAttribute(key string, value interface{})
+	// RecordError marks the span as failed.
+	RecordError(err error)
+	// End ends the span.
+	End()
+}
+
+// keys of the attributes set by the sdk
+const (
+	AttributeMethod        = "cryptomarket.method"          // http method or json-rpc method
+	AttributeEndpoint      = "cryptomarket.endpoint"        // rest endpoint, ids replaced by ":id"
+	AttributeRequestID     = "cryptomarket.request_id"      // json-rpc id of a websocket request
+	AttributeSymbol        = "cryptomarket.symbol"          // symbol param
+	AttributeClientOrderID = "cryptomarket.client_order_id" // clientOrderId param
+	AttributeResultID      = "cryptomarket.result_id"       // id of the result, see audit.ResultID
+	AttributeErrorCode     = "cryptomarket.error_code"      // error code of the exchange
+)
+
+// Noop is a tracer whose spans do nothing.
+var Noop Tracer = noop{}
+
+type noop struct{}
+
+func (noop) Start(ctx context.Context, name string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(key string, value interface{}) {}
+func (noopSpan) RecordError(err error)                      {}
+func (noopSpan) End()                                       {}
+
+// SetParams annotates a span with the params of a call identifying what it
+// is about: the symbol and the client order id.
+func SetParams(span Span, params map[string]interface{}) {
+	if symbol, ok := params["symbol"]; ok {
+		span.SetAttribute(AttributeSymbol, symbol)
+	}
+	if clientOrderID, ok := params["clientOrderId"]; ok {
+		span.SetAttribute(AttributeClientOrderID, clientOrderID)
+	}
+}
@@ -6,11 +6,13 @@ import (
 	"fmt"
 
 	"github.com/cryptomarket/cryptomarket-go/args"
+	"github.com/cryptomarket/cryptomarket-go/auth"
+	"github.com/cryptomarket/cryptomarket-go/credentials"
 
 	"github.com/cryptomarket/cryptomarket-go/models"
 )
 
-// AccountClient connects via websocket to cryptomarket to get account information of the user. uses SHA256 as auth method and authenticates automatically.
+// AccountClient connects via websocket to cryptomarket to get account information of the user. authenticates automatically, with HS256 unless another signer is given.
 type AccountClient struct {
 	clientBase
 }
@@ -18,7 +20,58 @@ type AccountClient struct {
 // NewAccountClient returns a new chan client if the connection with the
 // cryptomarket server is successful and if the authentication is successful.
 // return error otherwise.
-func NewAccountClient(apiKey, apiSecret string) (*AccountClient, error) {
+func NewAccountClient(apiKey, apiSecret string, options ...Option) (*AccountClient, error) {
+	return NewAccountClientWithSigner(auth.HS256(apiKey, apiSecret), options...)
+}
+
+// NewAccountClientWithSigner returns a new AccountClient logged in with the params
+// of signer, e.g. auth.Basic or an auth.External signer with the secret
+// kept out of the process.
+func NewAccountClientWithSigner(signer auth.Signer, options ...Option) (*AccountClient, error) {
+	return NewAccountClientContext(context.Background(), signer, options...)
+}
+
+// NewAccountClientContext returns a new AccountClient logged in with the params of
+// signer, with a context bounding the connection and the login.
+func NewAccountClientContext(ctx context.Context, signer auth.Signer, options ...Option) (*AccountClient, error) {
+	client := newAccountClient(newWSManager("/api/2/ws/account"))
+	client.apply(options)
+
+	// connect to streaming
+	if err := client.wsManager.connect(ctx); err != nil {
+		return nil, fmt.Errorf("Error in websocket client connection: %s", err)
+	}
+	// handle incomming data
+	go client.handle(client.wsManager.rcv)
+
+	if err := client.authenticate(ctx, signer); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// SetCredentials logs in again with a new api key and secret, signing with
+// HS256, on the same connection. Subscriptions and pending requests are kept.
+// Returns the error of the exchange if the new credentials are rejected.
+func (client *AccountClient) SetCredentials(apiKey, apiSecret string) error {
+	return client.SetSigner(auth.HS256(apiKey, apiSecret))
+}
+
+// SetSigner logs in again with a new signer on the same connection.
+// Subscriptions and pending requests are kept.
+func (client *AccountClient) SetSigner(signer auth.Signer) error {
+	return client.SetSignerContext(context.Background(), signer)
+}
+
+// SetSignerContext is SetSigner with a context bounding the login, instead
+// of the timeout of the client.
+func (client *AccountClient) SetSignerContext(ctx context.Context, signer auth.Signer) error {
+	return client.relogin(ctx, signer)
+}
+
+// newAccountClient builds a AccountClient over a manager, without connecting.
+func newAccountClient(manager *wsManager) *AccountClient {
 	methodMapping := map[string]string{
 		// transaction
 		"unsubscribeTransactions": "transaction",
@@ -29,10 +82,12 @@ func NewAccountClient(apiKey, apiSecret string) (*AccountClient, error) {
 		"subscribeBalance":   "balance",
 		"balance":            "balance",
 	}
-	client := &AccountClient{
+	return &AccountClient{
 		clientBase: clientBase{
-			wsManager: newWSManager("/api/2/ws/account"),
-			chanCache: newChanCache(),
+			wsManager:    manager,
+			chanCache:    newChanCache(),
+			capabilities: auth.AllCapabilities,
+			timeout:      defaultTimeout,
 			subscriptionKeysFunc: func(method string, params map[string]interface{}) (string, bool) {
 				val, ok := methodMapping[method]
 				return val, ok
@@ -42,18 +97,16 @@ func NewAccountClient(apiKey, apiSecret string) (*AccountClient, error) {
 			},
 		},
 	}
+}
 
-	// connect to streaming
-	if err := client.wsManager.connect(); err != nil {
-		return nil, fmt.Errorf("Error in websocket client connection: %s", err)
-	}
-	// handle incomming data
-	go client.handle(client.wsManager.rcv)
-
-	if err := client.authenticate(apiKey, apiSecret); err != nil {
+// NewAccountClientFromProvider returns a new AccountClient authenticated with the
+// credentials of a provider, such as credentials.Default("").
+func NewAccountClientFromProvider(ctx context.Context, provider credentials.Provider, options ...Option) (*AccountClient, error) {
+	keys, err := provider.Retrieve(ctx)
+	if err != nil {
 		return nil, err
 	}
-	return client, nil
+	return NewAccountClientContext(ctx, auth.HS256(keys.APIKey, keys.APISecret), options...)
 }
 
 // GetAccountBalance gets the account balance
@@ -153,31 +206,69 @@ func (client *AccountClient) LoadTransactions(ctx context.Context, arguments ...
 //
 // https://api.exchange.cryptomkt.com/#subscription-to-the-transactions
 func (client *AccountClient) SubscribeToTransactions() (feedCh chan models.Transaction, err error) {
-	dataCh, err := client.doSubscription("subscribeTransactions", nil, nil)
+	return client.SubscribeToTransactionsContext(context.Background())
+}
+
+// SubscribeToTransactionsContext is SubscribeToTransactions with a context bounding the subscription request,
+// instead of the timeout of the client.
+func (client *AccountClient) SubscribeToTransactionsContext(ctx context.Context) (feedCh chan models.Transaction, err error) {
+	feedCh, _, err = client.subscribeToTransactions(ctx, nil)
+	return feedCh, err
+}
+
+// TransactionsSubscription is a subscription to the transactions of the account, with its feed in C.
+type TransactionsSubscription struct {
+	*Subscription
+	C <-chan models.Transaction
+}
+
+// TransactionsSubscription is SubscribeToTransactionsContext returning the handle of the
+// subscription, with the arguments of the feed.
+//
+// Arguments:
+//  Buffer(int)            // Optional. Size of the buffer of the feed channel
+//  Overflow(OverflowType) // Optional. Policy of the feed channel when full. Default is OverflowTypeBlock
+func (client *AccountClient) TransactionsSubscription(ctx context.Context, arguments ...args.Argument) (*TransactionsSubscription, error) {
+	feedCh, sub, err := client.subscribeToTransactions(ctx, arguments)
 	if err != nil {
 		return nil, err
 	}
-	feedCh = make(chan models.Transaction)
-	go func() {
-		defer close(feedCh)
+	return &TransactionsSubscription{Subscription: sub, C: feedCh}, nil
+}
+
+func (client *AccountClient) subscribeToTransactions(ctx context.Context, arguments []args.Argument) (chan models.Transaction, *Subscription, error) {
+	sub, c, err := client.newSubscription(ctx, methodSubscribeTransactions, methodUnsubscribeTransactions, arguments, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	feedCh := make(chan models.Transaction, sub.buffer)
+	go sub.run(c, feedCh, func(data []byte) error {
 		var resp struct {
 			Params models.Transaction
 		}
-		for data := range dataCh {
-			json.Unmarshal(data, &resp)
-			feedCh <- resp.Params
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return err
 		}
-	}()
-	return feedCh, nil
+		sub.send(resp.Params)
+		return nil
+	})
+	return feedCh, sub, nil
 }
 
 // UnsubscribeToTransactions unsubscribe to the transaction feed.
 //
-// It also closes the feedCh of the subscription
+// It also closes the feedCh of every consumer of the subscription; consumers
+// sharing it should leave with the Unsubscribe of their handles instead.
 //
 // https://api.exchange.cryptomkt.com/#subscription-to-the-transactions
 func (client *AccountClient) UnsubscribeToTransactions() error {
-	return client.doUnsubscription("unsubscribeTransactions", nil, nil)
+	return client.UnsubscribeToTransactionsContext(context.Background())
+}
+
+// UnsubscribeToTransactionsContext is UnsubscribeToTransactions with a context bounding the unsubscription request,
+// instead of the timeout of the client.
+func (client *AccountClient) UnsubscribeToTransactionsContext(ctx context.Context) error {
+	return client.doUnsubscription(ctx, methodUnsubscribeTransactions, nil, nil)
 }
 
 // SubscribeToTransactions subscribes to a feed of transactions of the account.
@@ -192,29 +283,67 @@ func (client *AccountClient) UnsubscribeToTransactions() error {
 //
 // https://api.exchange.cryptomkt.com/#subscription-to-the-transactions
 func (client *AccountClient) SubscribeToBalance() (feedCh chan []models.Balance, err error) {
-	dataCh, err := client.doSubscription("subscribeBalance", nil, nil)
+	return client.SubscribeToBalanceContext(context.Background())
+}
+
+// SubscribeToBalanceContext is SubscribeToBalance with a context bounding the subscription request,
+// instead of the timeout of the client.
+func (client *AccountClient) SubscribeToBalanceContext(ctx context.Context) (feedCh chan []models.Balance, err error) {
+	feedCh, _, err = client.subscribeToBalance(ctx, nil)
+	return feedCh, err
+}
+
+// BalanceSubscription is a subscription to the balance of the account, with its feed in C.
+type BalanceSubscription struct {
+	*Subscription
+	C <-chan []models.Balance
+}
+
+// BalanceSubscription is SubscribeToBalanceContext returning the handle of the
+// subscription, with the arguments of the feed.
+//
+// Arguments:
+//  Buffer(int)            // Optional. Size of the buffer of the feed channel
+//  Overflow(OverflowType) // Optional. Policy of the feed channel when full. Default is OverflowTypeBlock
+func (client *AccountClient) BalanceSubscription(ctx context.Context, arguments ...args.Argument) (*BalanceSubscription, error) {
+	feedCh, sub, err := client.subscribeToBalance(ctx, arguments)
 	if err != nil {
 		return nil, err
 	}
-	feedCh = make(chan []models.Balance)
-	go func() {
-		defer close(feedCh)
+	return &BalanceSubscription{Subscription: sub, C: feedCh}, nil
+}
+
+func (client *AccountClient) subscribeToBalance(ctx context.Context, arguments []args.Argument) (chan []models.Balance, *Subscription, error) {
+	sub, c, err := client.newSubscription(ctx, methodSubscribeBalance, methodUnsubscribeBalance, arguments, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	feedCh := make(chan []models.Balance, sub.buffer)
+	go sub.run(c, feedCh, func(data []byte) error {
 		var resp struct {
 			Params []models.Balance
 		}
-		for data := range dataCh {
-			json.Unmarshal(data, &resp)
-			feedCh <- resp.Params
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return err
 		}
-	}()
-	return feedCh, nil
+		sub.send(resp.Params)
+		return nil
+	})
+	return feedCh, sub, nil
 }
 
 // UnsubscribeToTransactions unsubscribe to the transaction feed.
 //
-// It also closes the feedCh of the subscription
+// It also closes the feedCh of every consumer of the subscription; consumers
+// sharing it should leave with the Unsubscribe of their handles instead.
 //
 // https://api.exchange.cryptomkt.com/#subscription-to-the-transactions
 func (client *AccountClient) UnsubscribeToBalance() error {
-	return client.doUnsubscription("unsubscribeBalance", nil, nil)
+	return client.UnsubscribeToBalanceContext(context.Background())
+}
+
+// UnsubscribeToBalanceContext is UnsubscribeToBalance with a context bounding the unsubscription request,
+// instead of the timeout of the client.
+func (client *AccountClient) UnsubscribeToBalanceContext(ctx context.Context) error {
+	return client.doUnsubscription(ctx, methodUnsubscribeBalance, nil, nil)
 }
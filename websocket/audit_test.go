@@ -0,0 +1,46 @@
+package websocket
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/cryptomarket/cryptomarket-go/args"
+	"github.com/cryptomarket/cryptomarket-go/audit"
+)
+
+func TestAudit(t *testing.T) {
+	manager := newFakeWSManager(func(notification wsNotification) []string {
+		if notification.Method == methodCancelOrder {
+			return []string{fmt.Sprintf(`{"jsonrpc":"2.0","error":{"code":20002,"message":"Order not found"},"id":%d}`, notification.ID)}
+		}
+		return []string{fmt.Sprintf(`{"jsonrpc":"2.0","result":{"id":1,"clientOrderId":"abc"},"id":%d}`, notification.ID)}
+	})
+	var buf bytes.Buffer
+	client := newTradingClient(manager)
+	client.apply([]Option{WithAudit(audit.NewLog(audit.WriterSink(&buf)))})
+	go client.handle(manager.rcv)
+	defer client.Close()
+
+	ctx := context.Background()
+	if _, err := client.GetActiveOrders(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.CreateOrder(ctx, args.ClientOrderID("abc"), args.Symbol("EOSETH"), args.Side(args.SideTypeSell), args.Quantity("0.01")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.CancelOrder(ctx, args.ClientOrderID("xyz")); err == nil {
+		t.Fatal("expected an error")
+	}
+	records, err := audit.ReadRecords(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = audit.Verify(records); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].ResultID != "abc" || records[1].Method != methodCancelOrder || records[1].Error == "" {
+		t.Fatalf("wrong records: %+v", records)
+	}
+}
@@ -0,0 +1,276 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/cryptomarket/cryptomarket-go/metrics"
+	"github.com/cryptomarket/cryptomarket-go/models"
+)
+
+// BookStats are the counters kept by a BookManager for each of its symbols.
+type BookStats struct {
+	Sequence     int64 // sequence of the last applied snapshot or update
+	Updates      int64 // snapshots and updates applied to the book
+	Gaps         int64 // sequence gaps detected in the feed
+	Resyncs      int64 // snapshots received after a gap
+	ResyncErrors int64 // failed resubscriptions after a gap
+}
+
+type managedBook struct {
+	cache       *orderbookCache
+	stats       BookStats
+	resyncing   bool
+	subscribing bool
+	consumer    *consumer // of the order book feed
+}
+
+// BookManager keeps the order books of a set of symbols over a single
+// PublicClient, using one goroutine for all of them.
+//
+// The manager owns the resynchronization of each book: when a sequence gap is
+// detected the book is marked as waiting and a new snapshot is requested to the
+// exchange. Books are not available while waiting for a snapshot.
+type BookManager struct {
+	client   *PublicClient
+	dataCh   chan []byte
+	updates  chan string
+	books    map[string]*managedBook
+	mutex    sync.RWMutex
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+// NewBookManager returns a new BookManager over the given client, subscribed
+// to the order books of the given symbols.
+func NewBookManager(client *PublicClient, symbols ...string) (*BookManager, error) {
+	manager := &BookManager{
+		client:  client,
+		dataCh:  make(chan []byte, 1),
+		updates: make(chan string, 1),
+		books:   make(map[string]*managedBook),
+		done:    make(chan struct{}),
+	}
+	go manager.run()
+	if err := manager.AddSymbols(symbols...); err != nil {
+		manager.Close()
+		return nil, err
+	}
+	return manager, nil
+}
+
+// AddSymbols subscribes the manager to the order books of the given symbols.
+// Symbols already managed are ignored.
+func (manager *BookManager) AddSymbols(symbols ...string) error {
+	return manager.AddSymbolsContext(context.Background(), symbols...)
+}
+
+// AddSymbolsContext is AddSymbols with a context bounding the subscriptions,
+// instead of the timeout of the client.
+func (manager *BookManager) AddSymbolsContext(ctx context.Context, symbols ...string) error {
+	for _, symbol := range symbols {
+		manager.mutex.Lock()
+		if _, ok := manager.books[symbol]; ok {
+			manager.mutex.Unlock()
+			continue
+		}
+		params := map[string]interface{}{"symbol": symbol}
+		book := &managedBook{cache: newOrderbookCache(), subscribing: true}
+		manager.books[symbol] = book
+		manager.mutex.Unlock()
+
+		// a book already subscribed by another consumer gets its snapshot replayed
+		c, first, err := manager.client.chanCache.join(ctx, manager.client.buildKey(methodSubscribeOrderbook, params), manager.dataCh, false)
+		if err == nil && first {
+			err = manager.client.call(ctx, methodSubscribeOrderbook, params)
+			manager.client.chanCache.settle(c, err)
+		}
+		if err != nil {
+			manager.mutex.Lock()
+			delete(manager.books, symbol)
+			manager.mutex.Unlock()
+			return fmt.Errorf("%s: %v", symbol, err)
+		}
+		manager.mutex.Lock()
+		book.consumer = c
+		book.subscribing = false
+		removed := manager.books[symbol] != book
+		manager.mutex.Unlock()
+		if removed {
+			// removed while subscribing
+			manager.leave(ctx, symbol, c)
+		}
+	}
+	return nil
+}
+
+// RemoveSymbols unsubscribes the manager from the order books of the given
+// symbols and drops their books. Symbols not managed are ignored.
+func (manager *BookManager) RemoveSymbols(symbols ...string) error {
+	return manager.RemoveSymbolsContext(context.Background(), symbols...)
+}
+
+// RemoveSymbolsContext is RemoveSymbols with a context bounding the
+// unsubscriptions, instead of the timeout of the client.
+func (manager *BookManager) RemoveSymbolsContext(ctx context.Context, symbols ...string) error {
+	for _, symbol := range symbols {
+		manager.mutex.Lock()
+		book, ok := manager.books[symbol]
+		delete(manager.books, symbol)
+		manager.mutex.Unlock()
+		if !ok || book.consumer == nil {
+			// a book still subscribing is left by AddSymbols
+			continue
+		}
+		if err := manager.leave(ctx, symbol, book.consumer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// leave leaves the order book feed of a symbol, unsubscribing from the
+// exchange if no other consumer shares it.
+func (manager *BookManager) leave(ctx context.Context, symbol string, c *consumer) error {
+	err := manager.client.chanCache.leave(c, func() error {
+		return manager.client.call(ctx, methodUnsubscribeOrderbook, map[string]interface{}{"symbol": symbol})
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %v", symbol, err)
+	}
+	return nil
+}
+
+// Symbols returns the symbols managed, sorted.
+func (manager *BookManager) Symbols() []string {
+	manager.mutex.RLock()
+	defer manager.mutex.RUnlock()
+	symbols := make([]string, 0, len(manager.books))
+	for symbol := range manager.books {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// Book returns a copy of the current order book of a symbol. Returns false if
+// the symbol is not managed or if its book is waiting for a snapshot.
+func (manager *BookManager) Book(symbol string) (models.OrderBook, bool) {
+	manager.mutex.RLock()
+	defer manager.mutex.RUnlock()
+	book, ok := manager.books[symbol]
+	if !ok || book.cache.obWaiting() {
+		return models.OrderBook{}, false
+	}
+	return book.cache.orderBook(), true
+}
+
+// Stats returns the counters of a symbol. Returns false if the symbol is not managed.
+func (manager *BookManager) Stats(symbol string) (BookStats, bool) {
+	manager.mutex.RLock()
+	defer manager.mutex.RUnlock()
+	book, ok := manager.books[symbol]
+	if !ok {
+		return BookStats{}, false
+	}
+	return book.stats, true
+}
+
+// Updates returns a channel with the symbols whose book changed.
+//
+// Notifications are dropped while the channel is full, so a consumer may
+// receive a single notification for several changes and should read the book
+// with Book.
+func (manager *BookManager) Updates() <-chan string {
+	return manager.updates
+}
+
+// Close unsubscribes from all the managed books and stops the manager.
+// The Updates channel is closed.
+//
+// The feed channel is not closed, as the handler may still hold it; its buffer
+// absorbs the last message in flight. Closing a closed manager does nothing.
+func (manager *BookManager) Close() error {
+	err := manager.RemoveSymbols(manager.Symbols()...)
+	manager.doneOnce.Do(func() { close(manager.done) })
+	return err
+}
+
+func (manager *BookManager) run() {
+	defer close(manager.updates)
+	var resp struct {
+		Method string
+		Params struct {
+			Symbol string
+		}
+	}
+	for {
+		select {
+		case <-manager.done:
+			return
+		case data := <-manager.dataCh:
+			json.Unmarshal(data, &resp)
+			if manager.apply(resp.Method, resp.Params.Symbol, data) {
+				select {
+				case manager.updates <- resp.Params.Symbol:
+				default:
+				}
+			}
+		}
+	}
+}
+
+// apply applies a feed message to the book of a symbol, and reports if the book changed.
+func (manager *BookManager) apply(method, symbol string, data []byte) bool {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	book, ok := manager.books[symbol]
+	if !ok {
+		return false
+	}
+	if method == methodSnapshotOrderbook {
+		book.cache.obSnapshot(data)
+		if book.resyncing {
+			book.resyncing = false
+			book.stats.Resyncs++
+		}
+	} else {
+		book.cache.obUpdate(data)
+	}
+	if book.cache.obBroken() {
+		book.cache.waitOBSnapshot()
+		book.stats.Gaps++
+		manager.client.wsManager.metrics.Add(metrics.WSOrderbookGaps, metrics.Labels{"path": manager.client.wsManager.streamPath, "symbol": symbol}, 1)
+	}
+	// a book waiting outside of a subscription or a resync lost its snapshot,
+	// either by a gap or by a failed resync.
+	if book.cache.obWaiting() && !book.resyncing && !book.subscribing {
+		book.resyncing = true
+		manager.client.wsManager.metrics.Add(metrics.WSOrderbookResyncs, metrics.Labels{"path": manager.client.wsManager.streamPath, "symbol": symbol}, 1)
+		go manager.resync(symbol)
+	}
+	if book.cache.obWaiting() {
+		return false
+	}
+	book.stats.Updates++
+	book.stats.Sequence = book.cache.orderbook.Sequence
+	return true
+}
+
+// resync requests a new snapshot of the book of a symbol. It runs on its own
+// goroutine, as the response is delivered by the handler feeding the manager.
+func (manager *BookManager) resync(symbol string) {
+	err := manager.client.call(context.Background(), methodSubscribeOrderbook, map[string]interface{}{"symbol": symbol})
+	if err == nil {
+		return
+	}
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	if book, ok := manager.books[symbol]; ok {
+		book.resyncing = false
+		book.stats.ResyncErrors++
+	}
+}
@@ -0,0 +1,79 @@
+package websocket
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+)
+
+func orderbookMessage(method, symbol string, sequence int64, askPrice, askSize string) string {
+	return fmt.Sprintf(
+		`{"jsonrpc":"2.0","method":"%s","params":{"symbol":"%s","sequence":%d,"timestamp":"2021-01-20T20:01:00.612Z","ask":[{"price":"%s","size":"%s"}],"bid":[{"price":"0.9","size":"1"}]}}`,
+		method, symbol, sequence, askPrice, askSize,
+	)
+}
+
+func TestBookManagerResync(t *testing.T) {
+	var subscriptions int64
+	manager := newFakeWSManager(func(notification wsNotification) []string {
+		response := fmt.Sprintf(`{"jsonrpc":"2.0","result":true,"id":%d}`, notification.ID)
+		if notification.Method != methodSubscribeOrderbook {
+			return []string{response}
+		}
+		sequence := 10 * atomic.AddInt64(&subscriptions, 1)
+		symbol := notification.Params["symbol"].(string)
+		return []string{response, orderbookMessage(methodSnapshotOrderbook, symbol, sequence, "1.1", "1")}
+	})
+	client := newPublicClient(manager)
+	go client.handle(manager.rcv)
+	defer client.Close()
+
+	bookManager, err := NewBookManager(client, "EOSETH", "ETHBTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if symbols := bookManager.Symbols(); len(symbols) != 2 {
+		t.Fatalf("expected 2 symbols, got %v", symbols)
+	}
+	if !waitFor(func() bool { _, ok := bookManager.Book("EOSETH"); return ok }) {
+		t.Fatal("no snapshot applied")
+	}
+	stats, _ := bookManager.Stats("EOSETH")
+	sequence := stats.Sequence
+
+	manager.rcv <- []byte(orderbookMessage(methodUpdateOrderbook, "EOSETH", sequence+1, "1.0", "2"))
+	if !waitFor(func() bool { stats, _ := bookManager.Stats("EOSETH"); return stats.Sequence == sequence+1 }) {
+		t.Fatal("update not applied")
+	}
+	book, _ := bookManager.Book("EOSETH")
+	if len(book.Ask) != 2 || book.Ask[0].Price != "1.0" {
+		t.Fatalf("wrong ask side after update: %v", book.Ask)
+	}
+
+	// a gap in the sequence should trigger a new snapshot
+	manager.rcv <- []byte(orderbookMessage(methodUpdateOrderbook, "EOSETH", sequence+3, "1.0", "3"))
+	if !waitFor(func() bool { stats, _ := bookManager.Stats("EOSETH"); return stats.Resyncs == 1 }) {
+		t.Fatal("book not resynced")
+	}
+	stats, _ = bookManager.Stats("EOSETH")
+	if stats.Gaps != 1 || stats.ResyncErrors != 0 {
+		t.Fatalf("wrong stats: %+v", stats)
+	}
+	if _, ok := bookManager.Book("EOSETH"); !ok {
+		t.Fatal("book not available after resync")
+	}
+
+	if err = bookManager.RemoveSymbols("ETHBTC"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := bookManager.Book("ETHBTC"); ok {
+		t.Fatal("removed symbol still has a book")
+	}
+	if err = bookManager.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// e.g. from a defer and an error path
+	if err = bookManager.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,266 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cryptomarket/cryptomarket-go/args"
+	"github.com/cryptomarket/cryptomarket-go/models"
+)
+
+// CandleEventType is the type of change of a CandleSeries
+type CandleEventType string
+
+// candle event types
+const (
+	// CandleEventSnapshot signals the series was (re)initialized by a snapshot. Carries the current candle.
+	CandleEventSnapshot CandleEventType = "snapshot"
+	// CandleEventUpdated signals the current (open) candle changed. Carries the current candle.
+	CandleEventUpdated CandleEventType = "updated"
+	// CandleEventClosed signals a candle closed as a newer one opened. Carries the closed candle.
+	CandleEventClosed CandleEventType = "closed"
+)
+
+// CandleEvent is a change of a CandleSeries
+type CandleEvent struct {
+	Type   CandleEventType
+	Candle models.Candle
+}
+
+// CandleSource is a source of historical candles, like the rest client.
+type CandleSource interface {
+	GetCandlesOfSymbol(ctx context.Context, arguments ...args.Argument) ([]models.Candle, error)
+}
+
+// candleWindow is an ordered window of candles, the last one being the
+// current open candle.
+type candleWindow struct {
+	size    int
+	candles []models.Candle
+}
+
+func candleTime(candle models.Candle) time.Time {
+	t, _ := time.Parse(time.RFC3339, candle.Timestamp)
+	return t
+}
+
+// snapshot merges the candles of a snapshot, replacing the ones with the
+// same timestamp and keeping older history.
+func (window *candleWindow) snapshot(candles []models.Candle) []CandleEvent {
+	window.merge(candles, true)
+	if len(window.candles) == 0 {
+		return nil
+	}
+	return []CandleEvent{{Type: CandleEventSnapshot, Candle: window.candles[len(window.candles)-1]}}
+}
+
+// update applies the candles of an update, returning the events to emit.
+// Candles older than the current one are corrections to the history and emit no events.
+func (window *candleWindow) update(candles []models.Candle) []CandleEvent {
+	events := make([]CandleEvent, 0, len(candles))
+	for _, candle := range sortedCandles(candles) {
+		last := len(window.candles) - 1
+		switch {
+		case last < 0 || candleTime(candle).After(candleTime(window.candles[last])):
+			if last >= 0 {
+				events = append(events, CandleEvent{Type: CandleEventClosed, Candle: window.candles[last]})
+			}
+			window.candles = append(window.candles, candle)
+			events = append(events, CandleEvent{Type: CandleEventUpdated, Candle: candle})
+		case candleTime(candle).Equal(candleTime(window.candles[last])):
+			window.candles[last] = candle
+			events = append(events, CandleEvent{Type: CandleEventUpdated, Candle: candle})
+		default:
+			window.merge([]models.Candle{candle}, true)
+		}
+	}
+	window.trim()
+	return events
+}
+
+// merge adds the candles to the window. Candles with a timestamp already in
+// the window replace the existing one only if replace is true.
+func (window *candleWindow) merge(candles []models.Candle, replace bool) {
+	byTime := make(map[int64]int, len(window.candles))
+	for idx, candle := range window.candles {
+		byTime[candleTime(candle).UnixNano()] = idx
+	}
+	for _, candle := range candles {
+		if idx, ok := byTime[candleTime(candle).UnixNano()]; ok {
+			if replace {
+				window.candles[idx] = candle
+			}
+			continue
+		}
+		byTime[candleTime(candle).UnixNano()] = len(window.candles)
+		window.candles = append(window.candles, candle)
+	}
+	window.candles = sortedCandles(window.candles)
+	window.trim()
+}
+
+func (window *candleWindow) trim() {
+	if window.size > 0 && len(window.candles) > window.size {
+		window.candles = append([]models.Candle(nil), window.candles[len(window.candles)-window.size:]...)
+	}
+}
+
+func sortedCandles(candles []models.Candle) []models.Candle {
+	sort.SliceStable(candles, func(i, j int) bool {
+		return candleTime(candles[i]).Before(candleTime(candles[j]))
+	})
+	return candles
+}
+
+// CandleSeries is a window of candles of a symbol and period, kept up to date
+// by a candles subscription. The last candle of the series is the current
+// (open) candle, the previous ones are closed.
+type CandleSeries struct {
+	symbol   string
+	period   args.PeriodType
+	client   *PublicClient
+	consumer *consumer // of the candles feed
+	window   candleWindow
+	events   chan CandleEvent
+	mutex    sync.RWMutex
+}
+
+// SubscribeToCandleSeries subscribes to the candles of a symbol and keeps
+// them in a CandleSeries of at most size candles. A size of 0 keeps all the candles.
+//
+// The events of the series must be consumed, as the feed blocks when the
+// buffer of the Events channel is full.
+//
+// https://api.exchange.cryptomarket.com/#subscribe-to-candles
+//
+// Arguments:
+//  Symbol(string)     // The symbol of the candles to subscribe
+//  Period(PeriodType) // A valid tick interval. A PeriodType
+//  Limit(int)         // Optional. Maximum number of candles in the first feed.
+func (client *PublicClient) SubscribeToCandleSeries(size int, arguments ...args.Argument) (*CandleSeries, error) {
+	return client.SubscribeToCandleSeriesContext(context.Background(), size, arguments...)
+}
+
+// SubscribeToCandleSeriesContext is SubscribeToCandleSeries with a context bounding the subscription request,
+// instead of the timeout of the client.
+func (client *PublicClient) SubscribeToCandleSeriesContext(ctx context.Context, size int, arguments ...args.Argument) (*CandleSeries, error) {
+	params, err := args.BuildParams(arguments, "symbol", "period")
+	if err != nil {
+		return nil, err
+	}
+	series := &CandleSeries{
+		symbol: params["symbol"].(string),
+		period: args.PeriodType(fmt.Sprint(params["period"])),
+		client: client,
+		window: candleWindow{size: size},
+		events: make(chan CandleEvent, 16),
+	}
+	c, err := client.doSubscription(ctx, methodSubscribeCandles, arguments, []string{"symbol", "period"})
+	if err != nil {
+		return nil, err
+	}
+	series.consumer = c
+	go func() {
+		defer close(series.events)
+		for {
+			var data []byte
+			select {
+			case data = <-c.ch:
+			case <-c.done:
+				return
+			}
+			var resp struct {
+				Method string
+				Params struct {
+					Data []models.Candle
+				}
+			}
+			json.Unmarshal(data, &resp)
+			for _, event := range series.apply(resp.Method, resp.Params.Data) {
+				select {
+				case series.events <- event:
+				case <-c.done:
+					return
+				}
+			}
+		}
+	}()
+	return series, nil
+}
+
+func (series *CandleSeries) apply(method string, candles []models.Candle) []CandleEvent {
+	series.mutex.Lock()
+	defer series.mutex.Unlock()
+	if method == methodSnapshotCandles {
+		return series.window.snapshot(candles)
+	}
+	return series.window.update(candles)
+}
+
+// Symbol returns the symbol of the series
+func (series *CandleSeries) Symbol() string {
+	return series.symbol
+}
+
+// Period returns the period of the series
+func (series *CandleSeries) Period() args.PeriodType {
+	return series.period
+}
+
+// Events returns the channel of changes of the series. It is closed when the
+// subscription ends.
+func (series *CandleSeries) Events() <-chan CandleEvent {
+	return series.events
+}
+
+// Candles returns a copy of the candles of the series, oldest first.
+func (series *CandleSeries) Candles() []models.Candle {
+	series.mutex.RLock()
+	defer series.mutex.RUnlock()
+	return append([]models.Candle(nil), series.window.candles...)
+}
+
+// Current returns the current (open) candle. Returns false if the series is empty.
+func (series *CandleSeries) Current() (models.Candle, bool) {
+	series.mutex.RLock()
+	defer series.mutex.RUnlock()
+	if len(series.window.candles) == 0 {
+		return models.Candle{}, false
+	}
+	return series.window.candles[len(series.window.candles)-1], true
+}
+
+// Backfill completes the history of the series with candles from source,
+// usually a rest client. Candles already in the series are kept, as the
+// subscription is fresher than the source.
+func (series *CandleSeries) Backfill(ctx context.Context, source CandleSource) error {
+	limit := series.window.size
+	if limit == 0 || limit > 1000 {
+		limit = 1000
+	}
+	candles, err := source.GetCandlesOfSymbol(
+		ctx,
+		args.Symbol(series.symbol),
+		args.Period(series.period),
+		args.Sort(args.SortTypeDESC),
+		args.Limit(limit),
+	)
+	if err != nil {
+		return fmt.Errorf("CryptomarketSDKError: backfill failed: %v", err)
+	}
+	series.mutex.Lock()
+	defer series.mutex.Unlock()
+	series.window.merge(candles, false)
+	return nil
+}
+
+// Close unsubscribes the series from the candles feed, leaving it to the
+// other consumers, if any. The Events channel is closed.
+func (series *CandleSeries) Close() error {
+	params := map[string]interface{}{"symbol": series.symbol, "period": series.period}
+	return series.client.unsubscribe(context.Background(), series.consumer, methodUnsubscribeCandles, params)
+}
@@ -0,0 +1,95 @@
+package websocket
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cryptomarket/cryptomarket-go/args"
+	"github.com/cryptomarket/cryptomarket-go/models"
+)
+
+type fakeCandleSource []models.Candle
+
+func (source fakeCandleSource) GetCandlesOfSymbol(ctx context.Context, arguments ...args.Argument) ([]models.Candle, error) {
+	return source, nil
+}
+
+func candleAt(minute int, close string) models.Candle {
+	timestamp := time.Date(2021, 1, 20, 20, minute, 0, 0, time.UTC).Format("2006-01-02T15:04:05.000Z")
+	return models.Candle{Timestamp: timestamp, Open: "1", Close: close, Min: "1", Max: close, Volume: "1", VolumeQuote: "1"}
+}
+
+func receiveCandleEvent(t *testing.T, events <-chan CandleEvent) CandleEvent {
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no candle event received")
+	}
+	return CandleEvent{}
+}
+
+func TestCandleWindow(t *testing.T) {
+	window := candleWindow{size: 3}
+	events := window.snapshot([]models.Candle{candleAt(2, "2"), candleAt(1, "1")})
+	if len(events) != 1 || events[0].Type != CandleEventSnapshot || events[0].Candle.Close != "2" {
+		t.Fatalf("wrong snapshot events: %v", events)
+	}
+	events = window.update([]models.Candle{candleAt(2, "3")})
+	if len(events) != 1 || events[0].Type != CandleEventUpdated || events[0].Candle.Close != "3" {
+		t.Fatalf("wrong update events: %v", events)
+	}
+	events = window.update([]models.Candle{candleAt(3, "4"), candleAt(4, "5")})
+	expected := []CandleEventType{CandleEventClosed, CandleEventUpdated, CandleEventClosed, CandleEventUpdated}
+	if len(events) != len(expected) {
+		t.Fatalf("wrong number of events: %v", events)
+	}
+	for idx, event := range events {
+		if event.Type != expected[idx] {
+			t.Fatalf("wrong event %v: %v", idx, event)
+		}
+	}
+	if events[0].Candle.Close != "3" {
+		t.Fatalf("wrong closed candle: %v", events[0].Candle)
+	}
+	if len(window.candles) != 3 || window.candles[0].Close != "3" {
+		t.Fatalf("window not trimmed: %v", window.candles)
+	}
+}
+
+func TestCandleSeries(t *testing.T) {
+	client, manager := newFakePublicClient()
+	defer client.Close()
+	series, err := client.SubscribeToCandleSeries(10, args.Symbol("EOSETH"), args.Period(args.PeriodType1Minutes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager.rcv <- []byte(`{"method":"snapshotCandles","params":{"symbol":"EOSETH","period":"M1","data":[
+		{"timestamp":"2021-01-20T20:05:00.000Z","open":"1","close":"2","min":"1","max":"2","volume":"1","volumeQuote":"1"}]}}`)
+	if event := receiveCandleEvent(t, series.Events()); event.Type != CandleEventSnapshot {
+		t.Fatalf("expected a snapshot, got %v", event)
+	}
+	manager.rcv <- []byte(`{"method":"updateCandles","params":{"symbol":"EOSETH","period":"M1","data":[
+		{"timestamp":"2021-01-20T20:06:00.000Z","open":"2","close":"3","min":"2","max":"3","volume":"1","volumeQuote":"1"}]}}`)
+	if event := receiveCandleEvent(t, series.Events()); event.Type != CandleEventClosed || event.Candle.Close != "2" {
+		t.Fatalf("expected the first candle to close, got %v", event)
+	}
+	if event := receiveCandleEvent(t, series.Events()); event.Type != CandleEventUpdated || event.Candle.Close != "3" {
+		t.Fatalf("expected the new current candle, got %v", event)
+	}
+
+	if err = series.Backfill(context.Background(), fakeCandleSource{candleAt(4, "9"), candleAt(5, "9")}); err != nil {
+		t.Fatal(err)
+	}
+	candles := series.Candles()
+	if len(candles) != 3 || candles[0].Close != "9" || candles[1].Close != "2" {
+		t.Fatalf("wrong backfilled candles: %v", candles)
+	}
+	if current, ok := series.Current(); !ok || current.Close != "3" {
+		t.Fatalf("wrong current candle: %v", current)
+	}
+	if err = series.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
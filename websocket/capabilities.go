@@ -0,0 +1,60 @@
+package websocket
+
+import (
+	"github.com/cryptomarket/cryptomarket-go/auth"
+)
+
+// WithCapabilities restricts the client to a set of capabilities, e.g.
+// auth.ReadOnly. Requests and subscriptions out of them fail locally with an
+// *auth.CapabilityError, without reaching the exchange. Default is
+// auth.AllCapabilities.
+func WithCapabilities(capabilities auth.Capability) Option {
+	return func(client *clientBase) {
+		client.capabilities = capabilities
+	}
+}
+
+// methodCapabilities are the capabilities needed by the requests and
+// subscriptions. Methods not listed need all of them.
+var methodCapabilities = map[string]auth.Capability{
+	// public
+	methodGetCurrencies:      auth.MarketData,
+	methodGetCurrency:        auth.MarketData,
+	methodGetSymbols:         auth.MarketData,
+	methodGetSymbol:          auth.MarketData,
+	methodGetTrades:          auth.MarketData,
+	methodSubscribeTicker:    auth.MarketData,
+	methodSubscribeOrderbook: auth.MarketData,
+	methodSubscribeTrades:    auth.MarketData,
+	methodSubscribeCandles:   auth.MarketData,
+	// unsubscriptions need the capability of their subscription
+	methodUnsubcribeTicker:     auth.MarketData,
+	methodUnsubscribeOrderbook: auth.MarketData,
+	methodUnsubscribeTrades:    auth.MarketData,
+	methodUnsubscribeCandles:   auth.MarketData,
+	// trading
+	methodNewOrder:          auth.Trade,
+	methodCancelOrder:       auth.Trade,
+	methodReplaceOrder:      auth.Trade,
+	methodGetOrders:         auth.ReadAccount,
+	methodGetTradingBalance: auth.ReadAccount,
+	methodSubscribeReports:  auth.ReadAccount,
+	// account
+	methodGetBalance:            auth.ReadAccount,
+	methodFindTransactions:      auth.ReadAccount,
+	methodLoadTransactions:      auth.ReadAccount,
+	methodSubscribeTransactions: auth.ReadAccount,
+	methodSubscribeBalance:      auth.ReadAccount,
+	// unsubscriptions
+	methodUnsubscribeTransactions: auth.ReadAccount,
+	methodUnsubscribeBalance:      auth.ReadAccount,
+}
+
+// checkCapability fails if the client is not allowed to call method.
+func (client *clientBase) checkCapability(method string) error {
+	required, ok := methodCapabilities[method]
+	if !ok {
+		required = auth.AllCapabilities
+	}
+	return auth.Check(method, required, client.capabilities)
+}
@@ -0,0 +1,64 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/cryptomarket/cryptomarket-go/args"
+	"github.com/cryptomarket/cryptomarket-go/auth"
+)
+
+func TestReadOnlyTradingClient(t *testing.T) {
+	sent := make(chan string, 2)
+	manager := newFakeWSManager(func(notification wsNotification) []string {
+		sent <- notification.Method
+		return []string{fmt.Sprintf(`{"jsonrpc":"2.0","result":[],"id":%d}`, notification.ID)}
+	})
+	client := newTradingClient(manager)
+	client.apply([]Option{WithCapabilities(auth.ReadOnly)})
+	go client.handle(manager.rcv)
+	defer client.Close()
+
+	if _, err := client.GetActiveOrders(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	_, err := client.CreateOrder(context.Background(), args.ClientOrderID("abc"), args.Symbol("EOSETH"), args.Side(args.SideTypeSell), args.Quantity("0.01"))
+	var capabilityErr *auth.CapabilityError
+	if !errors.As(err, &capabilityErr) || capabilityErr.Required != auth.Trade {
+		t.Fatalf("expected a capability error, got %v", err)
+	}
+	if _, err = client.SubscribeToReports(); err != nil {
+		t.Fatal(err)
+	}
+	if <-sent != methodGetOrders || <-sent != methodSubscribeReports {
+		t.Fatal("a rejected request reached the server")
+	}
+}
+
+func TestMarketDataBookManager(t *testing.T) {
+	manager := newFakeWSManager(func(notification wsNotification) []string {
+		return []string{fmt.Sprintf(`{"jsonrpc":"2.0","result":true,"id":%d}`, notification.ID)}
+	})
+	client := newPublicClient(manager)
+	client.apply([]Option{WithCapabilities(auth.MarketData)})
+	go client.handle(manager.rcv)
+	defer client.Close()
+
+	books, err := NewBookManager(client, "EOSETH", "ETHBTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = books.RemoveSymbols("EOSETH"); err != nil {
+		t.Fatal(err)
+	}
+	if err = books.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, method := range []string{methodUnsubcribeTicker, methodUnsubscribeOrderbook, methodUnsubscribeTrades, methodUnsubscribeCandles} {
+		if err = client.checkCapability(method); err != nil {
+			t.Errorf("%v: %v", method, err)
+		}
+	}
+}
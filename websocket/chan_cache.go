@@ -1,11 +1,24 @@
 package websocket
 
-import "sync"
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/cryptomarket/cryptomarket-go/metrics"
+)
 
 type chanCache struct {
 	currentID int64
 	chans     *sync.Map
 	idLock    *sync.Mutex
+	pending   int64 // requests waiting for a response
+	metrics   metrics.Recorder
+	labels    metrics.Labels
+	feeds     map[string]*feed // subscriptions, by key
+	feedsLock sync.Mutex
 }
 
 func newChanCache() *chanCache {
@@ -13,6 +26,8 @@ func newChanCache() *chanCache {
 		currentID: 1,
 		chans:     new(sync.Map),
 		idLock:    new(sync.Mutex),
+		metrics:   metrics.Discard,
+		feeds:     make(map[string]*feed),
 	}
 }
 
@@ -22,6 +37,15 @@ func (cache *chanCache) close() {
 		close(ch)
 		return true
 	})
+	cache.feedsLock.Lock()
+	feeds := cache.feeds
+	cache.feeds = make(map[string]*feed)
+	cache.feedsLock.Unlock()
+	for _, f := range feeds {
+		for _, c := range f.consumers {
+			c.leave()
+		}
+	}
 }
 
 func (cache *chanCache) nextID() int64 {
@@ -38,30 +62,270 @@ func (cache *chanCache) nextID() int64 {
 func (cache *chanCache) store(ch chan []byte) int64 {
 	id := cache.nextID()
 	cache.chans.Store(id, ch)
+	cache.metrics.Set(metrics.WSPendingRequests, cache.labels, float64(atomic.AddInt64(&cache.pending, 1)))
 	return id
 }
 
 func (cache *chanCache) pop(id int64) (chan []byte, bool) {
 	if val, ok := cache.chans.LoadAndDelete(id); ok {
+		cache.metrics.Set(metrics.WSPendingRequests, cache.labels, float64(atomic.AddInt64(&cache.pending, -1)))
 		return val.(chan []byte), ok
 	}
 	return nil, false
 }
 
-func (cache *chanCache) getSubcriptionCh(key string) (chan []byte, bool) {
-	if val, ok := cache.chans.Load(key); ok {
-		return val.(chan []byte), ok
+// consumer is a subscriber of a feed, with its own channel. The channel is
+// not closed when the consumer leaves, done is.
+type consumer struct {
+	key      string
+	feed     *feed
+	ch       chan []byte
+	raw      bool // of a raw subscription
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+func (c *consumer) leave() {
+	c.doneOnce.Do(func() { close(c.done) })
+}
+
+// deliver sends data to the consumer, unless it leaves first.
+func (c *consumer) deliver(data []byte) {
+	select {
+	case c.ch <- data:
+	case <-c.done:
 	}
-	return nil, false
 }
 
-func (cache *chanCache) storeSubscriptionCh(key string, ch chan []byte) {
-	if val, ok := cache.chans.Load(key); ok {
-		close(val.(chan []byte)) // close old channel
+// feed is a subscription to the exchange shared by its consumers. The
+// subscription and the unsubscription of a key with the exchange are
+// serialized: consumers joining a feed wait for its subscription, and the
+// ones joining a closing feed wait for its unsubscription to start a new one.
+type feed struct {
+	consumers []*consumer
+	snapshot  []byte        // the last one, replayed to the consumers joining later
+	pending   chan struct{} // closed when the subscription is settled
+	err       error         // of the subscription, set before closing pending
+	closing   chan struct{} // closed when the unsubscription is done, nil while open
+}
+
+func newFeed() *feed {
+	return &feed{pending: make(chan struct{})}
+}
+
+// remove takes a consumer out of the feed, reporting if it was there.
+func (f *feed) remove(c *consumer) bool {
+	for idx, other := range f.consumers {
+		if other == c {
+			f.consumers = append(f.consumers[:idx], f.consumers[idx+1:]...)
+			return true
+		}
 	}
-	cache.chans.Store(key, ch)
+	return false
 }
 
-func (cache *chanCache) deleteSubscriptionCh(key string) {
-	cache.chans.Delete(key)
-}
\ No newline at end of file
+// join adds a consumer receiving on ch to the feed of key, raw if it is the
+// consumer of a raw subscription. first reports if
+// the feed is new, in which case the caller subscribes to the exchange and
+// settles the feed with the result. Otherwise join waits for the
+// subscription of the feed, returning its error if it failed.
+func (cache *chanCache) join(ctx context.Context, key string, ch chan []byte, raw bool) (c *consumer, first bool, err error) {
+	for {
+		cache.feedsLock.Lock()
+		f, ok := cache.feeds[key]
+		if ok && f.closing != nil {
+			closing := f.closing
+			cache.feedsLock.Unlock()
+			select {
+			case <-closing:
+				continue
+			case <-ctx.Done():
+				return nil, false, fmt.Errorf("CryptomarketSDKError: %v not subscribed: %w", key, ctx.Err())
+			}
+		}
+		if !ok {
+			f = newFeed()
+			cache.feeds[key] = f
+		}
+		c = &consumer{key: key, feed: f, ch: ch, raw: raw, done: make(chan struct{})}
+		f.consumers = append(f.consumers, c)
+		if f.snapshot != nil {
+			select {
+			case ch <- f.snapshot:
+			default:
+				go c.deliver(f.snapshot)
+			}
+		}
+		cache.feedsLock.Unlock()
+		if !ok {
+			return c, true, nil
+		}
+		select {
+		case <-f.pending:
+		case <-ctx.Done():
+			cache.feedsLock.Lock()
+			f.remove(c)
+			cache.feedsLock.Unlock()
+			c.leave()
+			return nil, false, fmt.Errorf("CryptomarketSDKError: %v not subscribed: %w", key, ctx.Err())
+		}
+		if f.err != nil {
+			c.leave()
+			return nil, false, f.err
+		}
+		return c, false, nil
+	}
+}
+
+// settle ends the subscription of the feed of the first consumer, with the
+// result of the subscription to the exchange. A failed feed is removed along
+// with all its consumers.
+func (cache *chanCache) settle(c *consumer, err error) {
+	f := c.feed
+	var consumers []*consumer
+	cache.feedsLock.Lock()
+	f.err = err
+	if err != nil {
+		consumers = f.consumers
+		f.consumers = nil
+		if cache.feeds[c.key] == f {
+			delete(cache.feeds, c.key)
+		}
+	}
+	cache.feedsLock.Unlock()
+	for _, other := range consumers {
+		other.leave()
+	}
+	close(f.pending)
+}
+
+// leave removes the consumer from its feed. The last consumer closes the
+// feed with unsubscribe, e.g. the unsubscription of the exchange, returning
+// its error. unsubscribe may be nil.
+func (cache *chanCache) leave(c *consumer, unsubscribe func() error) error {
+	c.leave()
+	f := c.feed
+	cache.feedsLock.Lock()
+	if !f.remove(c) || len(f.consumers) > 0 || cache.feeds[c.key] != f || f.closing != nil {
+		cache.feedsLock.Unlock()
+		return nil
+	}
+	f.closing = make(chan struct{})
+	cache.feedsLock.Unlock()
+	return cache.closeFeed(c.key, f, unsubscribe)
+}
+
+// drop removes the feed of key along with all its consumers, closing it with
+// unsubscribe, even if there is no such feed.
+func (cache *chanCache) drop(key string, unsubscribe func() error) error {
+	for {
+		cache.feedsLock.Lock()
+		f, ok := cache.feeds[key]
+		if ok {
+			wait := f.closing
+			if wait == nil {
+				select {
+				case <-f.pending:
+				default:
+					wait = f.pending
+				}
+			}
+			if wait != nil {
+				cache.feedsLock.Unlock()
+				<-wait
+				continue
+			}
+		} else {
+			f = newFeed()
+			close(f.pending)
+			cache.feeds[key] = f
+		}
+		consumers := f.consumers
+		f.consumers = nil
+		f.closing = make(chan struct{})
+		cache.feedsLock.Unlock()
+		for _, c := range consumers {
+			c.leave()
+		}
+		return cache.closeFeed(key, f, unsubscribe)
+	}
+}
+
+// leaveRaw removes the consumers of raw subscriptions from the feed of key,
+// closing it with unsubscribe if they were the last ones. Without a feed the
+// feed is closed with unsubscribe anyway, like drop does.
+func (cache *chanCache) leaveRaw(key string, unsubscribe func() error) error {
+	for {
+		cache.feedsLock.Lock()
+		f, ok := cache.feeds[key]
+		if !ok {
+			f = newFeed()
+			close(f.pending)
+			f.closing = make(chan struct{})
+			cache.feeds[key] = f
+			cache.feedsLock.Unlock()
+			return cache.closeFeed(key, f, unsubscribe)
+		}
+		wait := f.closing
+		if wait == nil {
+			select {
+			case <-f.pending:
+			default:
+				wait = f.pending
+			}
+		}
+		if wait != nil {
+			cache.feedsLock.Unlock()
+			<-wait
+			continue
+		}
+		var raws []*consumer
+		for _, c := range f.consumers {
+			if c.raw {
+				raws = append(raws, c)
+			}
+		}
+		cache.feedsLock.Unlock()
+		var err error
+		for _, c := range raws {
+			if leaveErr := cache.leave(c, unsubscribe); leaveErr != nil {
+				err = leaveErr
+			}
+		}
+		return err
+	}
+}
+
+// closeFeed runs the unsubscription of a closing feed, and then removes it.
+func (cache *chanCache) closeFeed(key string, f *feed, unsubscribe func() error) error {
+	var err error
+	if unsubscribe != nil {
+		err = unsubscribe()
+	}
+	cache.feedsLock.Lock()
+	if cache.feeds[key] == f {
+		delete(cache.feeds, key)
+	}
+	cache.feedsLock.Unlock()
+	close(f.closing)
+	return err
+}
+
+// publish delivers a notification to the consumers of the feed of key,
+// keeping it if it is a snapshot. Returns false if there is no such feed.
+func (cache *chanCache) publish(key, method string, data []byte) bool {
+	cache.feedsLock.Lock()
+	f, ok := cache.feeds[key]
+	var consumers []*consumer
+	if ok {
+		if strings.HasPrefix(method, "snapshot") {
+			f.snapshot = data
+		}
+		consumers = append(consumers, f.consumers...)
+	}
+	cache.feedsLock.Unlock()
+	for _, c := range consumers {
+		c.deliver(data)
+	}
+	return ok
+}
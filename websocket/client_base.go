@@ -2,14 +2,16 @@ package websocket
 
 import (
 	"context"
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/cryptomarket/cryptomarket-go/args"
+	"github.com/cryptomarket/cryptomarket-go/audit"
+	"github.com/cryptomarket/cryptomarket-go/auth"
+	"github.com/cryptomarket/cryptomarket-go/metrics"
 )
 
 const (
@@ -22,6 +24,10 @@ type clientBase struct {
 	chanCache            *chanCache
 	subscriptionKeysFunc func(string, map[string]interface{}) (string, bool)
 	keyFromResponse      func(wsResponse) string
+	loginMutex           sync.Mutex // serializes the logins of a connection
+	capabilities         auth.Capability
+	timeout              time.Duration // of the requests without deadline, zero for none
+	audit                *audit.Log
 }
 
 // Close close all the channels related to the client as well as the websocket connection.
@@ -37,14 +43,22 @@ func (client *clientBase) handle(rcvCh chan []byte) {
 		json.Unmarshal(data, &resp)
 		if resp.ID != 0 {
 			if ch, ok := client.chanCache.pop(resp.ID); ok {
-				defer close(ch)
 				ch <- data
+				close(ch)
 			}
 		} else if resp.Method != "" {
 			key := client.keyFromResponse(resp)
-			if feedCh, ok := client.chanCache.getSubcriptionCh(key); ok {
-				feedCh <- data
+			// a feed of a raw subscription of a method not wrapped by the client
+			rawKey := rawKeyOf(resp.Method, resp.Params.Symbol)
+			typed := client.chanCache.publish(key, resp.Method, data)
+			raw := client.chanCache.publish(rawKey, resp.Method, data)
+			if !typed && !raw {
+				continue
 			}
+			if !typed {
+				key = rawKey
+			}
+			client.wsManager.metrics.Add(metrics.WSMessages, metrics.Labels{"path": client.wsManager.streamPath, "feed": key}, 1)
 		}
 	}
 }
@@ -71,14 +85,43 @@ func (client *clientBase) buildKey(method string, params map[string]interface{})
 	return "subscription"
 }
 
-func (client *clientBase) doRequest(ctx context.Context, method string, arguments []args.Argument, requiredArguments []string, model interface{}) error {
-	params, err := args.BuildParams(arguments, requiredArguments...)
-	if err != nil {
-		return err
+// localParams are the params used by the client itself to shape a
+// subscription feed. They are never sent to the exchange.
+var localParams = []string{"depth", "grouping", "buffer", "overflow"}
+
+// splitLocalArguments separates the local params of the arguments from the
+// ones to send to the exchange.
+func splitLocalArguments(arguments []args.Argument) ([]args.Argument, map[string]interface{}) {
+	params, _ := args.BuildParams(arguments)
+	local := make(map[string]interface{})
+	for _, key := range localParams {
+		if val, ok := params[key]; ok {
+			local[key] = val
+			delete(params, key)
+		}
+	}
+	remote := func(remoteParams map[string]interface{}) {
+		for key, val := range params {
+			remoteParams[key] = val
+		}
 	}
+	return []args.Argument{remote}, local
+}
+
+// errConnectionClosed is returned by the requests over a closed connection.
+var errConnectionClosed = fmt.Errorf("CryptomarketSDKError: websocket connection closed")
+
+// roundTrip sends a request and waits for its response. It gives up when ctx
+// is done or, if ctx has no deadline, when the timeout of the client passes,
+// removing the request from the pending ones. sent reports if the request
+// left the client. The error of the exchange in the response, if any, is
+// returned along with it.
+func (client *clientBase) roundTrip(ctx context.Context, method string, params map[string]interface{}) (response []byte, sent bool, err error) {
 	if !client.wsManager.isOpen {
-		return fmt.Errorf("CryptomarketSDKError: websocket connection closed")
+		return nil, false, errConnectionClosed
 	}
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
 	ch := make(chan []byte, 1)
 	id := client.chanCache.store(ch)
 	notification := wsNotification{
@@ -88,144 +131,188 @@ func (client *clientBase) doRequest(ctx context.Context, method string, argument
 	}
 	data, err := json.Marshal(notification)
 	if err != nil {
-		if ch, ok := client.chanCache.pop(id); ok {
-			close(ch)
-		}
-		return fmt.Errorf("CryptomarketSDKError: invalid notification: %v", err)
+		client.chanCache.pop(id)
+		return nil, false, fmt.Errorf("CryptomarketSDKError: invalid notification: %v", err)
+	}
+	span := client.startSpan(ctx, method, id, params)
+	select {
+	case client.wsManager.snd <- data:
+	case <-ctx.Done():
+		client.chanCache.pop(id)
+		err = fmt.Errorf("CryptomarketSDKError: %v not sent: %w", method, ctx.Err())
+		endSpan(span, nil, err)
+		return nil, false, err
 	}
-	client.wsManager.snd <- data
 	select {
 	case <-ctx.Done():
-		if ch, ok := client.chanCache.pop(id); ok {
-			close(ch)
+		// the response, if it ever comes, is dropped by the handler
+		client.chanCache.pop(id)
+		err = fmt.Errorf("CryptomarketSDKError: no response to %v: %w", method, ctx.Err())
+		endSpan(span, nil, err)
+		return nil, true, err
+	case data, ok := <-ch:
+		if !ok {
+			endSpan(span, nil, errConnectionClosed)
+			return nil, true, errConnectionClosed
 		}
-		return ctx.Err()
-	case data := <-ch:
 		var resp struct {
 			Error APIError
 		}
 		json.Unmarshal(data, &resp)
 		if resp.Error != nil {
-			return fmt.Errorf("CryptomarketAPIError: %v", resp.Error)
+			err = fmt.Errorf("CryptomarketAPIError: %v", resp.Error)
 		}
-		json.Unmarshal(data, model)
-		return nil
+		endSpan(span, data, err)
+		return data, true, err
 	}
 }
 
-func (client *clientBase) doSubscription(method string, arguments []args.Argument, requiredArguments []string) (chan []byte, error) {
+// withTimeout bounds ctx by the timeout of the client, unless ctx already
+// has a deadline.
+func (client *clientBase) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || client.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, client.timeout)
+}
+
+func (client *clientBase) doRequest(ctx context.Context, method string, arguments []args.Argument, requiredArguments []string, model interface{}) error {
 	params, err := args.BuildParams(arguments, requiredArguments...)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	if !client.wsManager.isOpen {
-		return nil, fmt.Errorf("CryptomarketSDKError: websocket connection closed")
+	data, err := client.exchange(ctx, method, params)
+	if err != nil {
+		return err
 	}
-	ch := make(chan []byte, 1)
-	id := client.chanCache.store(ch)
-	notification := wsNotification{
-		ID:     id,
-		Method: method,
-		Params: params,
+	json.Unmarshal(data, model)
+	return nil
+}
+
+// exchange checks the capabilities of a request, makes its round trip and
+// records it in the audit log.
+func (client *clientBase) exchange(ctx context.Context, method string, params map[string]interface{}) ([]byte, error) {
+	if err := client.checkCapability(method); err != nil {
+		return nil, err
 	}
-	data, err := json.Marshal(notification)
-	if err != nil {
-		if ch, ok := client.chanCache.pop(id); ok {
-			close(ch)
-		}
-		return nil, fmt.Errorf("CryptomarketSDKError: invalid notification: %v", err)
+	data, sent, err := client.roundTrip(ctx, method, params)
+	if sent {
+		// a request sent without response may have been executed
+		err = client.record(method, params, data, err)
 	}
-	key := client.buildKey(method, params)
-	dataOut := make(chan []byte, 1)
-	client.chanCache.storeSubscriptionCh(key, dataOut)
-	client.wsManager.snd <- data
-	data = <-ch
-	var resp struct {
-		Error APIError
+	return data, err
+}
+
+// record writes the call to the audit log if it changes the account,
+// returning the error of the call, or the error of the log if the call
+// succeeded.
+func (client *clientBase) record(method string, params map[string]interface{}, response []byte, callErr error) error {
+	if client.audit == nil || !auditedMethods[method] {
+		return callErr
 	}
-	json.Unmarshal(data, &resp)
-	if resp.Error != nil {
-		close(dataOut)
-		return nil, fmt.Errorf("CryptomarketAPIError: %v", resp.Error)
+	if err := client.audit.Append(method, params, audit.ResultID(response), callErr); err != nil && callErr == nil {
+		return err
 	}
-	return dataOut, nil
+	return callErr
 }
 
-func (client *clientBase) doUnsubscription(method string, arguments []args.Argument, requiredArguments []string) error {
+// doSubscription joins the subscription of method, subscribing to the
+// exchange if there is no other consumer of it.
+func (client *clientBase) doSubscription(ctx context.Context, method string, arguments []args.Argument, requiredArguments []string) (*consumer, error) {
+	if err := client.checkCapability(method); err != nil {
+		return nil, err
+	}
 	params, err := args.BuildParams(arguments, requiredArguments...)
 	if err != nil {
-		return err
-	}
-	if !client.wsManager.isOpen {
-		return fmt.Errorf("CryptomarketSDKError: websocket connection closed")
+		return nil, err
 	}
-	key := client.buildKey(method, params)
-	if ch, ok := client.chanCache.getSubcriptionCh(key); ok {
-		client.chanCache.deleteSubscriptionCh(key)
-		close(ch)
+	return client.subscribe(ctx, method, client.buildKey(method, params), params, false)
+}
+
+// subscribe joins the feed of key, subscribing to the exchange with method
+// and params if it is the first consumer of the feed. raw tells the consumers
+// of raw subscriptions apart.
+func (client *clientBase) subscribe(ctx context.Context, method, key string, params map[string]interface{}, raw bool) (*consumer, error) {
+	c, first, err := client.chanCache.join(ctx, key, make(chan []byte, 1), raw)
+	if err != nil {
+		return nil, err
 	}
-	ch := make(chan []byte, 1)
-	id := client.chanCache.store(ch)
-	notification := wsNotification{
-		ID:     id,
-		Method: method,
-		Params: params,
+	if !first {
+		return c, nil
 	}
-	data, err := json.Marshal(notification)
+	_, _, err = client.roundTrip(ctx, method, params)
+	client.chanCache.settle(c, err)
 	if err != nil {
-		if ch, ok := client.chanCache.pop(id); ok {
-			close(ch)
-		}
-		return fmt.Errorf("CryptomarketSDKError: invalid notification: %v", err)
+		return nil, err
 	}
-	client.wsManager.snd <- data
-	data = <-ch
-	var resp struct {
-		Error APIError
+	return c, nil
+}
+
+// unsubscribe removes a consumer from its feed, unsubscribing from the
+// exchange with method and params if it was the last one. Without method the
+// feed is only left.
+func (client *clientBase) unsubscribe(ctx context.Context, c *consumer, method string, params map[string]interface{}) error {
+	if method == "" {
+		return client.chanCache.leave(c, nil)
 	}
-	json.Unmarshal(data, &resp)
-	if resp.Error != nil {
-		return fmt.Errorf("CryptomarketAPIError: %v", resp.Error)
+	return client.chanCache.leave(c, func() error {
+		return client.exchangeUnsubscription(ctx, method, params)
+	})
+}
+
+// exchangeUnsubscription sends an unsubscription to the exchange, checked
+// against the capabilities of the client like any other request.
+func (client *clientBase) exchangeUnsubscription(ctx context.Context, method string, params map[string]interface{}) error {
+	if !client.wsManager.isOpen {
+		return errConnectionClosed
 	}
-	return nil
+	_, err := client.exchange(ctx, method, params)
+	return err
 }
 
-func (client *clientBase) authenticate(apiKey, apiSecret string) (err error) {
+// doUnsubscription unsubscribes from the exchange, ending the feeds of all the
+// consumers of the subscription.
+func (client *clientBase) doUnsubscription(ctx context.Context, method string, arguments []args.Argument, requiredArguments []string) error {
+	params, err := args.BuildParams(arguments, requiredArguments...)
+	if err != nil {
+		return err
+	}
 	if !client.wsManager.isOpen {
-		return fmt.Errorf("CryptomarketSDKError: websocket connection closed")
-	}
-	nonce := makeNonce(30)
-	h := hmac.New(sha256.New, []byte(apiSecret))
-	h.Write([]byte(nonce))
-	signature := hex.EncodeToString(h.Sum(nil))
-	params := map[string]interface{}{
-		"algo":      "HS256",
-		"pKey":      apiKey,
-		"nonce":     nonce,
-		"signature": signature,
+		return errConnectionClosed
 	}
-	ch := make(chan []byte, 1)
-	id := client.chanCache.store(ch)
-	notification := wsNotification{
-		ID:     id,
-		Method: "login",
-		Params: params,
+	return client.chanCache.drop(client.buildKey(method, params), func() error {
+		return client.exchangeUnsubscription(ctx, method, params)
+	})
+}
+
+// call sends a request to the server and waits for its response, discarding
+// any result. Only the error of the response, if any, is returned.
+func (client *clientBase) call(ctx context.Context, method string, params map[string]interface{}) error {
+	_, err := client.exchange(ctx, method, params)
+	return err
+}
+
+func (client *clientBase) authenticate(ctx context.Context, signer auth.Signer) error {
+	if !client.wsManager.isOpen {
+		return errConnectionClosed
 	}
-	data, err := json.Marshal(notification)
+	params, err := signer.LoginParams(ctx, makeNonce(30))
 	if err != nil {
-		if ch, ok := client.chanCache.pop(id); ok {
-			close(ch)
-		}
-		return fmt.Errorf("CryptomarketSDKError: invalid notification: %v", err)
-	}
-	client.wsManager.snd <- data
-	data = <-ch
-	var resp struct {
-		Error APIError
+		return err
 	}
-	json.Unmarshal(data, &resp)
-	if resp.Error != nil {
-		return fmt.Errorf("CryptomarketAPIError: %v", resp.Error)
+	_, _, err = client.roundTrip(ctx, "login", params)
+	return err
+}
+
+// relogin logs in again on the open connection, e.g. with rotated
+// credentials. Subscriptions and pending requests are kept.
+func (client *clientBase) relogin(ctx context.Context, signer auth.Signer) error {
+	client.loginMutex.Lock()
+	defer client.loginMutex.Unlock()
+	if err := client.authenticate(ctx, signer); err != nil {
+		client.wsManager.logger.Error("login failed", "path", client.wsManager.streamPath, "error", err)
+		return err
 	}
+	client.wsManager.logger.Info("logged in again", "path", client.wsManager.streamPath)
 	return nil
 }
@@ -1,12 +1,13 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"math/big"
 	"time"
 
+	"github.com/cryptomarket/cryptomarket-go/credentials"
 	"github.com/cryptomarket/cryptomarket-go/models"
 )
 
@@ -15,13 +16,46 @@ type APIKeys struct {
 	APISecret string `json:"apiSecret"`
 }
 
+// LoadKeys loads the keys of the tests from the environment or the default
+// profile file, see the credentials package.
 func LoadKeys() (apiKeys APIKeys) {
-	data, err := ioutil.ReadFile("/home/ismael/cryptomarket/apis/keys.json")
+	keys, err := credentials.Default("").Retrieve(context.Background())
 	if err != nil {
 		fmt.Print(err)
 	}
-	err = json.Unmarshal(data, &apiKeys)
-	return
+	return APIKeys{APIKey: keys.APIKey, APISecret: keys.APISecret}
+}
+
+// newFakeWSManager returns an open wsManager not connected to the exchange.
+// Every sent notification is passed to respond, and the returned messages are
+// delivered as if received from the server. Messages can also be pushed
+// directly to the rcv channel of the manager.
+func newFakeWSManager(respond func(notification wsNotification) []string) *wsManager {
+	manager := newWSManager("")
+	manager.isOpen = true
+	go func() {
+		defer close(manager.rcv)
+		for data := range manager.snd {
+			var notification wsNotification
+			json.Unmarshal(data, &notification)
+			for _, msg := range respond(notification) {
+				manager.rcv <- []byte(msg)
+			}
+		}
+	}()
+	return manager
+}
+
+// waitFor polls cond until it is true or a second passes.
+func waitFor(cond func() bool) bool {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return false
 }
 
 const format string = "2006-01-02T15:04:05.000Z07:00"
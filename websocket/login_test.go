@@ -0,0 +1,87 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cryptomarket/cryptomarket-go/auth"
+)
+
+func TestLoginWithSigner(t *testing.T) {
+	logins := make(chan map[string]interface{}, 1)
+	manager := newFakeWSManager(func(notification wsNotification) []string {
+		if notification.Method == "login" {
+			logins <- notification.Params
+		}
+		return []string{fmt.Sprintf(`{"jsonrpc":"2.0","result":true,"id":%d}`, notification.ID)}
+	})
+	client := newTradingClient(manager)
+	go client.handle(manager.rcv)
+	defer client.Close()
+
+	signed := ""
+	signer := auth.External("key", func(ctx context.Context, message string) (string, error) {
+		signed = message
+		return "signature", nil
+	})
+	if err := client.authenticate(context.Background(), signer); err != nil {
+		t.Fatal(err)
+	}
+	params := <-logins
+	if params["algo"] != "HS256" || params["pKey"] != "key" || params["signature"] != "signature" || params["nonce"] != signed {
+		t.Fatalf("wrong login params: %v", params)
+	}
+
+	failing := auth.External("key", func(ctx context.Context, message string) (string, error) {
+		return "", errors.New("sidecar down")
+	})
+	if err := client.authenticate(context.Background(), failing); err == nil {
+		t.Fatal("expected a signing error")
+	}
+}
+
+func TestSetCredentials(t *testing.T) {
+	keys := make(chan interface{}, 3)
+	manager := newFakeWSManager(func(notification wsNotification) []string {
+		if notification.Method == "login" {
+			keys <- notification.Params["pKey"]
+			if notification.Params["pKey"] == "revoked" {
+				return []string{fmt.Sprintf(`{"jsonrpc":"2.0","error":{"code":1002,"message":"Authorization failed"},"id":%d}`, notification.ID)}
+			}
+		}
+		return []string{fmt.Sprintf(`{"jsonrpc":"2.0","result":true,"id":%d}`, notification.ID)}
+	})
+	client := newTradingClient(manager)
+	go client.handle(manager.rcv)
+	defer client.Close()
+	if err := client.authenticate(context.Background(), auth.HS256("old", "secret")); err != nil {
+		t.Fatal(err)
+	}
+	feedCh, err := client.SubscribeToReports()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = client.SetCredentials("new", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if err = client.SetCredentials("revoked", "secret"); err == nil {
+		t.Fatal("expected a rejected login")
+	}
+	if <-keys != "old" || <-keys != "new" || <-keys != "revoked" {
+		t.Fatal("wrong logins")
+	}
+	// the subscription survives the rotation
+	manager.rcv <- []byte(`{"jsonrpc":"2.0","method":"activeOrders","params":[]}`)
+	manager.rcv <- []byte(`{"jsonrpc":"2.0","method":"report","params":{"id":1,"clientOrderId":"abc"}}`)
+	select {
+	case report := <-feedCh:
+		if report.ClientOrderID != "abc" {
+			t.Fatalf("wrong report: %+v", report)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no report after the rotation")
+	}
+}
@@ -49,6 +49,9 @@ const (
 	methodSubscribeTransactions   = "subscribeTransactions"
 	methodUnsubscribeTransactions = "unsubscribeTransactions"
 	methodUpdateTransaction       = "updateTransaction"
+
+	methodSubscribeBalance   = "subscribeBalance"
+	methodUnsubscribeBalance = "unsubscribeBalance"
 )
 
 const (
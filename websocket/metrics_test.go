@@ -0,0 +1,91 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cryptomarket/cryptomarket-go/args"
+	"github.com/cryptomarket/cryptomarket-go/metrics"
+)
+
+func TestMetrics(t *testing.T) {
+	hold := make(chan bool)
+	manager := newFakeWSManager(func(notification wsNotification) []string {
+		if notification.Method == methodGetCurrencies {
+			<-hold
+		}
+		return []string{fmt.Sprintf(`{"jsonrpc":"2.0","result":true,"id":%d}`, notification.ID)}
+	})
+	manager.streamPath = "/api/2/ws/public"
+	registry := metrics.NewRegistry()
+	client := newPublicClient(manager)
+	client.apply([]Option{WithMetrics(registry)})
+	go client.handle(manager.rcv)
+	defer client.Close()
+
+	pending := func() float64 {
+		return registry.Value(metrics.WSPendingRequests, metrics.Labels{"path": "/api/2/ws/public"})
+	}
+	done := make(chan bool)
+	go func() {
+		client.GetCurrencies(context.Background())
+		close(done)
+	}()
+	if !waitFor(func() bool { return pending() == 1 }) {
+		t.Fatalf("expected a pending request, got %v", pending())
+	}
+	close(hold)
+	<-done
+	if pending() != 0 {
+		t.Fatalf("expected no pending requests, got %v", pending())
+	}
+
+	feedCh, err := client.SubscribeToTicker(args.Symbol("EOSETH"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager.rcv <- []byte(`{"jsonrpc":"2.0","method":"ticker","params":{"symbol":"EOSETH"}}`)
+	<-feedCh
+	if registry.Value(metrics.WSMessages, metrics.Labels{"path": "/api/2/ws/public", "feed": "TICKERS:EOSETH:"}) != 1 {
+		t.Fatal("feed message not counted")
+	}
+}
+
+func TestOrderbookSubscriptionMetrics(t *testing.T) {
+	var subscriptions int64
+	manager := newFakeWSManager(func(notification wsNotification) []string {
+		response := fmt.Sprintf(`{"jsonrpc":"2.0","result":true,"id":%d}`, notification.ID)
+		if notification.Method != methodSubscribeOrderbook {
+			return []string{response}
+		}
+		sequence := 10 * atomic.AddInt64(&subscriptions, 1)
+		return []string{response, orderbookMessage(methodSnapshotOrderbook, "EOSETH", sequence, "1.1", "1")}
+	})
+	manager.streamPath = "/api/2/ws/public"
+	registry := metrics.NewRegistry()
+	client := newPublicClient(manager)
+	client.apply([]Option{WithMetrics(registry)})
+	go client.handle(manager.rcv)
+	defer client.Close()
+
+	sub, err := client.OrderbookSubscription(context.Background(), args.Symbol("EOSETH"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-sub.C
+	// a gap in the sequence of the first snapshot resubscribes for a new one
+	manager.rcv <- []byte(orderbookMessage(methodUpdateOrderbook, "EOSETH", 12, "1.0", "3"))
+	<-sub.C
+	if !waitFor(func() bool { return atomic.LoadInt64(&subscriptions) == 2 }) {
+		t.Fatal("not resubscribed")
+	}
+	labels := metrics.Labels{"path": "/api/2/ws/public", "symbol": "EOSETH"}
+	if gaps := registry.Value(metrics.WSOrderbookGaps, labels); gaps != 1 {
+		t.Fatalf("expected a gap, got %v", gaps)
+	}
+	if resyncs := registry.Value(metrics.WSOrderbookResyncs, labels); resyncs != 1 {
+		t.Fatalf("expected a resync, got %v", resyncs)
+	}
+}
@@ -0,0 +1,64 @@
+package websocket
+
+import (
+	"time"
+
+	"github.com/cryptomarket/cryptomarket-go/audit"
+	"github.com/cryptomarket/cryptomarket-go/logging"
+	"github.com/cryptomarket/cryptomarket-go/metrics"
+)
+
+// Option configures a client on creation.
+type Option func(*clientBase)
+
+func (client *clientBase) apply(options []Option) {
+	for _, option := range options {
+		option(client)
+	}
+}
+
+// defaultTimeout bounds the requests without a deadline.
+const defaultTimeout = 30 * time.Second
+
+// WithTimeout sets how long the requests whose context has no deadline, and
+// the ones of the methods without a context, wait for their response. Zero
+// waits forever. Default is 30 seconds.
+func WithTimeout(timeout time.Duration) Option {
+	return func(client *clientBase) {
+		client.timeout = timeout
+	}
+}
+
+// WithLogger sets the logger of the client, which logs connections, logins
+// and errors of the connection. Default is logging.Discard.
+func WithLogger(logger logging.Logger) Option {
+	return func(client *clientBase) {
+		client.wsManager.logger = logger
+	}
+}
+
+// WithMetrics records the feed messages, the disconnections and the pending
+// requests of the client, and the resyncs of its book managers.
+func WithMetrics(recorder metrics.Recorder) Option {
+	return func(client *clientBase) {
+		client.wsManager.metrics = recorder
+		client.chanCache.metrics = recorder
+		client.chanCache.labels = metrics.Labels{"path": client.wsManager.streamPath}
+	}
+}
+
+// WithAudit records the orders, cancels and replacements of a TradingClient,
+// along with the reply of the exchange, in log. A call that succeeds but
+// can't be recorded returns the error of the log.
+func WithAudit(log *audit.Log) Option {
+	return func(client *clientBase) {
+		client.audit = log
+	}
+}
+
+// auditedMethods are the methods changing the account.
+var auditedMethods = map[string]bool{
+	methodNewOrder:     true,
+	methodCancelOrder:  true,
+	methodReplaceOrder: true,
+}
@@ -133,6 +133,18 @@ func (cache *orderbookCache) getOrderbookData() []byte {
 	return data
 }
 
+// orderBook returns a copy of the cached orderbook that does not share
+// memory with the cache.
+func (cache *orderbookCache) orderBook() models.OrderBook {
+	ob := cache.orderbook
+	return models.OrderBook{
+		Symbol:    ob.Symbol,
+		Timestamp: ob.Timestamp,
+		Ask:       append([]models.BookLevel(nil), ob.Ask...),
+		Bid:       append([]models.BookLevel(nil), ob.Bid...),
+	}
+}
+
 func (cache *orderbookCache) obWaiting() bool {
 	return cache.orderbookState == orderbookStateWaiting
 }
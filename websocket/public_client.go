@@ -7,7 +7,10 @@ import (
 	"strings"
 
 	"github.com/cryptomarket/cryptomarket-go/args"
+	"github.com/cryptomarket/cryptomarket-go/auth"
+	"github.com/cryptomarket/cryptomarket-go/metrics"
 	"github.com/cryptomarket/cryptomarket-go/models"
+	orderbooks "github.com/cryptomarket/cryptomarket-go/orderbook"
 )
 
 // PublicClient connects via websocket to cryptomarket to get market information of the exchange.
@@ -18,7 +21,27 @@ type PublicClient struct {
 
 // NewPublicClient returns a new chan client if the connection with the
 // cryptomarket server is successful, and error otherwise.
-func NewPublicClient() (*PublicClient, error) {
+func NewPublicClient(options ...Option) (*PublicClient, error) {
+	return NewPublicClientContext(context.Background(), options...)
+}
+
+// NewPublicClientContext returns a new PublicClient, with a context bounding
+// the connection.
+func NewPublicClientContext(ctx context.Context, options ...Option) (*PublicClient, error) {
+	client := newPublicClient(newWSManager("/api/2/ws/public"))
+	client.apply(options)
+	// connect to streaming
+	err := client.wsManager.connect(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Error in websocket client connection: %s", err)
+	}
+	// handle incomming data
+	go client.handle(client.wsManager.rcv)
+	return client, nil
+}
+
+// newPublicClient builds a public client over the given manager, without connecting it.
+func newPublicClient(manager *wsManager) *PublicClient {
 	methodMapping := map[string]string{
 		// tickers
 		"subscribeTicker":   "tickers",
@@ -42,7 +65,11 @@ func NewPublicClient() (*PublicClient, error) {
 	}
 	keyFunc := func(method string, params map[string]interface{}) string {
 		methodKey := methodMapping[method]
-		period, _ := params["period"].(string)
+		period := ""
+		if val, ok := params["period"]; ok {
+			// either a string from a response or a PeriodType from the arguments
+			period = fmt.Sprint(val)
+		}
 
 		if methodKey == "candles" && period == "" { // default period
 			period = string(args.PeriodType30Minutes)
@@ -51,10 +78,12 @@ func NewPublicClient() (*PublicClient, error) {
 		key := methodKey + ":" + symbol + ":" + period
 		return strings.ToUpper(key)
 	}
-	client := &PublicClient{
+	return &PublicClient{
 		clientBase: clientBase{
-			wsManager: newWSManager("/api/2/ws/public"),
-			chanCache: newChanCache(),
+			wsManager:    manager,
+			chanCache:    newChanCache(),
+			capabilities: auth.AllCapabilities,
+			timeout:      defaultTimeout,
 			subscriptionKeysFunc: func(method string, params map[string]interface{}) (string, bool) {
 				return keyFunc(method, params), true
 			},
@@ -70,15 +99,6 @@ func NewPublicClient() (*PublicClient, error) {
 		},
 		obCache: newOrderbookCache(),
 	}
-
-	// connect to streaming
-	err := client.wsManager.connect()
-	if err != nil {
-		return nil, fmt.Errorf("Error in websocket client connection: %s", err)
-	}
-	// handle incomming data
-	go client.handle(client.wsManager.rcv)
-	return client, nil
 }
 
 // GetCurrencies gets a list all available currencies on the exchange
@@ -178,89 +198,190 @@ func (client *PublicClient) GetTrades(ctx context.Context, arguments ...args.Arg
 // https://api.exchange.cryptomarket.com/#subscribe-to-ticker
 //
 // Arguments:
-//  Symbol(string) // The symbol of the ticker to subscribe
+//  Symbol(string)         // The symbol of the ticker to subscribe
+//  Buffer(int)            // Optional. Size of the buffer of the feed channel. Not sent to the exchange
+//  Overflow(OverflowType) // Optional. Policy of the feed channel when full. Default is OverflowTypeBlock. Not sent to the exchange
 func (client *PublicClient) SubscribeToTicker(arguments ...args.Argument) (feedCh chan models.Ticker, err error) {
-	dataCh, err := client.doSubscription(methodSubscribeTicker, arguments, []string{"symbol"})
+	return client.SubscribeToTickerContext(context.Background(), arguments...)
+}
+
+// SubscribeToTickerContext is SubscribeToTicker with a context bounding the subscription request,
+// instead of the timeout of the client.
+func (client *PublicClient) SubscribeToTickerContext(ctx context.Context, arguments ...args.Argument) (feedCh chan models.Ticker, err error) {
+	feedCh, _, err = client.subscribeToTicker(ctx, arguments)
+	return feedCh, err
+}
+
+// TickerSubscription is a subscription to a ticker, with its feed in C.
+type TickerSubscription struct {
+	*Subscription
+	C <-chan models.Ticker
+}
+
+// TickerSubscription is SubscribeToTickerContext returning the handle of the
+// subscription.
+func (client *PublicClient) TickerSubscription(ctx context.Context, arguments ...args.Argument) (*TickerSubscription, error) {
+	feedCh, sub, err := client.subscribeToTicker(ctx, arguments)
 	if err != nil {
 		return nil, err
 	}
-	feedCh = make(chan models.Ticker)
-	go func() {
-		defer close(feedCh)
+	return &TickerSubscription{Subscription: sub, C: feedCh}, nil
+}
+
+func (client *PublicClient) subscribeToTicker(ctx context.Context, arguments []args.Argument) (chan models.Ticker, *Subscription, error) {
+	sub, c, err := client.newSubscription(ctx, methodSubscribeTicker, methodUnsubcribeTicker, arguments, []string{"symbol"})
+	if err != nil {
+		return nil, nil, err
+	}
+	feedCh := make(chan models.Ticker, sub.buffer)
+	go sub.run(c, feedCh, func(data []byte) error {
 		var resp struct {
 			Params models.Ticker
 		}
-		for data := range dataCh {
-			json.Unmarshal(data, &resp)
-			feedCh <- resp.Params
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return err
 		}
-	}()
-	return feedCh, nil
+		sub.send(resp.Params)
+		return nil
+	})
+	return feedCh, sub, nil
 }
 
 // UnsubscribeToTicker unsubscribes to a ticker of a symbol.
-// It also closes the feedCh of the subscription.
+// It also closes the feedCh of every consumer of the subscription; consumers
+// sharing it should leave with the Unsubscribe of their handles instead.
 //
 // https://api.exchange.cryptomarket.com/#subscribe-to-ticker
 //
 // Arguments:
 //  Symbol(string) // The symbol of the ticker to unsubscribe
 func (client *PublicClient) UnsubscribeToTicker(arguments ...args.Argument) error {
-	return client.doUnsubscription(methodUnsubcribeTicker, arguments, []string{"symbol"})
+	return client.UnsubscribeToTickerContext(context.Background(), arguments...)
+}
+
+// UnsubscribeToTickerContext is UnsubscribeToTicker with a context bounding the unsubscription request,
+// instead of the timeout of the client.
+func (client *PublicClient) UnsubscribeToTickerContext(ctx context.Context, arguments ...args.Argument) error {
+	return client.doUnsubscription(ctx, methodUnsubcribeTicker, arguments, []string{"symbol"})
 }
 
 // SubscribeToOrderbook subscribes to the order book of a symbol.
 // An Order Book is an electronic list of buy and sell orders for a specific symbol, structured by price level.
 //
+// The feed can be reduced to the top levels of the book or to levels grouped
+// into price buckets. Reduced feeds only emit when the reduced view changes.
+//
 // https://api.exchange.cryptomarket.com/#subscribe-to-order-book
 //
 // Arguments:
-//  Symbol(string) // The symbol of the orderbook to subscribe
+//  Symbol(string)         // The symbol of the orderbook to subscribe
+//  Depth(int)             // Optional. Maximum number of levels of each side of the book. Not sent to the exchange
+//  Grouping(string)       // Optional. Size of the price buckets grouping the levels, e.g. a multiple of the tick size. Not sent to the exchange
+//  Buffer(int)            // Optional. Size of the buffer of the feed channel. Not sent to the exchange
+//  Overflow(OverflowType) // Optional. Policy of the feed channel when full. Default is OverflowTypeBlock. Not sent to the exchange
 func (client *PublicClient) SubscribeToOrderbook(arguments ...args.Argument) (chan models.OrderBook, error) {
-	dataCh, err := client.doSubscription(methodSubscribeOrderbook, arguments, []string{"symbol"})
+	return client.SubscribeToOrderbookContext(context.Background(), arguments...)
+}
+
+// SubscribeToOrderbookContext is SubscribeToOrderbook with a context bounding the subscription request,
+// instead of the timeout of the client.
+func (client *PublicClient) SubscribeToOrderbookContext(ctx context.Context, arguments ...args.Argument) (chan models.OrderBook, error) {
+	feedCh, _, err := client.subscribeToOrderbook(ctx, arguments)
+	return feedCh, err
+}
+
+// OrderbookSubscription is a subscription to an order book, with its feed in C.
+type OrderbookSubscription struct {
+	*Subscription
+	C <-chan models.OrderBook
+}
+
+// OrderbookSubscription is SubscribeToOrderbookContext returning the handle
+// of the subscription.
+func (client *PublicClient) OrderbookSubscription(ctx context.Context, arguments ...args.Argument) (*OrderbookSubscription, error) {
+	feedCh, sub, err := client.subscribeToOrderbook(ctx, arguments)
 	if err != nil {
 		return nil, err
 	}
-	feedCh := make(chan models.OrderBook)
-	go func() {
-		obCache := newOrderbookCache()
-		defer close(feedCh)
+	return &OrderbookSubscription{Subscription: sub, C: feedCh}, nil
+}
+
+func (client *PublicClient) subscribeToOrderbook(ctx context.Context, arguments []args.Argument) (chan models.OrderBook, *Subscription, error) {
+	_, local := splitLocalArguments(arguments)
+	depth, _ := local["depth"].(int)
+	grouping, _ := local["grouping"].(string)
+	if grouping != "" {
+		if _, err := orderbooks.Aggregate(&models.OrderBook{}, grouping); err != nil {
+			return nil, nil, err
+		}
+	}
+	reduced := depth > 0 || grouping != ""
+	sub, c, err := client.newSubscription(ctx, methodSubscribeOrderbook, methodUnsubscribeOrderbook, arguments, []string{"symbol"})
+	if err != nil {
+		return nil, nil, err
+	}
+	feedCh := make(chan models.OrderBook, sub.buffer)
+	obCache := newOrderbookCache()
+	var last *models.OrderBook
+	go sub.run(c, feedCh, func(data []byte) error {
 		var resp struct {
 			Method string
 			Params struct {
 				Symbol string
 			}
 		}
-		for data := range dataCh {
-			json.Unmarshal(data, &resp)
-			if resp.Method == methodSnapshotOrderbook {
-				obCache.obSnapshot(data)
-			} else {
-				obCache.obUpdate(data)
-			}
-			if obCache.obBroken() {
-				obCache.waitOBSnapshot()
-				notification := wsNotification{
-					ID:     client.chanCache.nextID(),
-					Method: methodSubscribeOrderbook,
-					Params: map[string]interface{}{"symbol": resp.Params.Symbol},
-				}
-				requestData, _ := json.Marshal(notification)
-				client.wsManager.snd <- requestData
-			}
-			if obCache.obWaiting() {
-				continue
-			}
-			var orderbook models.OrderBook
-			json.Unmarshal(obCache.getOrderbookData(), &orderbook)
-			feedCh <- orderbook
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return err
+		}
+		if resp.Method == methodSnapshotOrderbook {
+			obCache.obSnapshot(data)
+		} else {
+			obCache.obUpdate(data)
+		}
+		if obCache.obBroken() {
+			obCache.waitOBSnapshot()
+			labels := metrics.Labels{"path": client.wsManager.streamPath, "symbol": resp.Params.Symbol}
+			client.wsManager.metrics.Add(metrics.WSOrderbookGaps, labels, 1)
+			client.wsManager.metrics.Add(metrics.WSOrderbookResyncs, labels, 1)
+			// resubscribing makes the server send a new snapshot. the call is made
+			// in its own goroutine as its response is delivered by the same handler
+			// feeding this loop.
+			go client.call(context.Background(), methodSubscribeOrderbook, map[string]interface{}{"symbol": resp.Params.Symbol})
+		}
+		if obCache.obWaiting() {
+			return nil
+		}
+		book := obCache.orderBook()
+		if !reduced {
+			sub.send(book)
+			return nil
+		}
+		view := reduceOrderbook(&book, depth, grouping)
+		if last != nil && orderbooks.EqualLevels(last.Ask, view.Ask) && orderbooks.EqualLevels(last.Bid, view.Bid) {
+			return nil
 		}
-	}()
-	return feedCh, nil
+		last = view
+		sub.send(*view)
+		return nil
+	})
+	return feedCh, sub, nil
+}
+
+// reduceOrderbook groups the levels of the book, if grouping is given, and
+// then limits its depth.
+func reduceOrderbook(book *models.OrderBook, depth int, grouping string) *models.OrderBook {
+	if grouping != "" {
+		// the grouping is validated on subscription, and the levels come from the exchange.
+		if grouped, err := orderbooks.Aggregate(book, grouping); err == nil {
+			book = grouped
+		}
+	}
+	return orderbooks.Truncate(book, depth)
 }
 
 // UnsubscribeToOrderbook unsubscribes to an order book of a symbol.
-// It also closes the feedCh of the subscription.
+// It also closes the feedCh of every consumer of the subscription; consumers
+// sharing it should leave with the Unsubscribe of their handles instead.
 //
 // An Order Book is an electronic list of buy and sell orders for a specific symbol, structured by price level
 //
@@ -269,7 +390,13 @@ func (client *PublicClient) SubscribeToOrderbook(arguments ...args.Argument) (ch
 // Arguments:
 //  Symbol(string) // The symbol of the orderbook to unsubscribe
 func (client *PublicClient) UnsubscribeToOrderbook(arguments ...args.Argument) error {
-	return client.doUnsubscription(methodUnsubscribeOrderbook, arguments, []string{"symbol"})
+	return client.UnsubscribeToOrderbookContext(context.Background(), arguments...)
+}
+
+// UnsubscribeToOrderbookContext is UnsubscribeToOrderbook with a context bounding the unsubscription request,
+// instead of the timeout of the client.
+func (client *PublicClient) UnsubscribeToOrderbookContext(ctx context.Context, arguments ...args.Argument) error {
+	return client.doUnsubscription(ctx, methodUnsubscribeOrderbook, arguments, []string{"symbol"})
 }
 
 // SubscribeToTrades subscribes to the trades of a symbol
@@ -277,38 +404,74 @@ func (client *PublicClient) UnsubscribeToOrderbook(arguments ...args.Argument) e
 // https://api.exchange.cryptomarket.com/#subscribe-to-trades
 //
 // Arguments:
-//  Symbol(string) // The symbol of the trades to subscribe
-//  Limit(int)     // Optional. Maximum number of trades in the first feed.
+//  Symbol(string)         // The symbol of the trades to subscribe
+//  Limit(int)             // Optional. Maximum number of trades in the first feed.
+//  Buffer(int)            // Optional. Size of the buffer of the feed channel. Not sent to the exchange
+//  Overflow(OverflowType) // Optional. Policy of the feed channel when full. Default is OverflowTypeBlock. Not sent to the exchange
 func (client *PublicClient) SubscribeToTrades(arguments ...args.Argument) (feedCh chan []models.PublicTrade, err error) {
-	dataCh, err := client.doSubscription(methodSubscribeTrades, arguments, []string{"symbol"})
+	return client.SubscribeToTradesContext(context.Background(), arguments...)
+}
+
+// SubscribeToTradesContext is SubscribeToTrades with a context bounding the subscription request,
+// instead of the timeout of the client.
+func (client *PublicClient) SubscribeToTradesContext(ctx context.Context, arguments ...args.Argument) (feedCh chan []models.PublicTrade, err error) {
+	feedCh, _, err = client.subscribeToTrades(ctx, arguments)
+	return feedCh, err
+}
+
+// TradesSubscription is a subscription to trades, with its feed in C.
+type TradesSubscription struct {
+	*Subscription
+	C <-chan []models.PublicTrade
+}
+
+// TradesSubscription is SubscribeToTradesContext returning the handle of the
+// subscription.
+func (client *PublicClient) TradesSubscription(ctx context.Context, arguments ...args.Argument) (*TradesSubscription, error) {
+	feedCh, sub, err := client.subscribeToTrades(ctx, arguments)
 	if err != nil {
 		return nil, err
 	}
-	feedCh = make(chan []models.PublicTrade)
-	go func() {
-		defer close(feedCh)
+	return &TradesSubscription{Subscription: sub, C: feedCh}, nil
+}
+
+func (client *PublicClient) subscribeToTrades(ctx context.Context, arguments []args.Argument) (chan []models.PublicTrade, *Subscription, error) {
+	sub, c, err := client.newSubscription(ctx, methodSubscribeTrades, methodUnsubscribeTrades, arguments, []string{"symbol"})
+	if err != nil {
+		return nil, nil, err
+	}
+	feedCh := make(chan []models.PublicTrade, sub.buffer)
+	go sub.run(c, feedCh, func(data []byte) error {
 		var resp struct {
 			Params struct {
 				Data []models.PublicTrade
 			}
 		}
-		for data := range dataCh {
-			json.Unmarshal(data, &resp)
-			feedCh <- resp.Params.Data
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return err
 		}
-	}()
-	return feedCh, nil
+		sub.send(resp.Params.Data)
+		return nil
+	})
+	return feedCh, sub, nil
 }
 
 // UnsubscribeToTrades unsubscribes to a trades of a symbol.
-// It also closes the feedCh of the subscription.
+// It also closes the feedCh of every consumer of the subscription; consumers
+// sharing it should leave with the Unsubscribe of their handles instead.
 //
 // https://api.exchange.cryptomarket.com/#subscribe-to-trades
 //
 // Arguments:
 //  Symbol(string) // The symbol of the trades to unsubscribe
 func (client *PublicClient) UnsubscribeToTrades(arguments ...args.Argument) error {
-	return client.doUnsubscription(methodUnsubscribeTrades, arguments, []string{"symbol"})
+	return client.UnsubscribeToTradesContext(context.Background(), arguments...)
+}
+
+// UnsubscribeToTradesContext is UnsubscribeToTrades with a context bounding the unsubscription request,
+// instead of the timeout of the client.
+func (client *PublicClient) UnsubscribeToTradesContext(ctx context.Context, arguments ...args.Argument) error {
+	return client.doUnsubscription(ctx, methodUnsubscribeTrades, arguments, []string{"symbol"})
 }
 
 // SubscribeToCandles subscribes to the candles of a symbol, at the given period
@@ -319,32 +482,62 @@ func (client *PublicClient) UnsubscribeToTrades(arguments ...args.Argument) erro
 // https://api.exchange.cryptomarket.com/#subscribe-to-candles
 //
 // Arguments:
-//  Symbol(string)     // The symbol of the candles to subscribe
-//  Period(PeriodType) // A valid tick interval. A PeriodType
-//  Limit(int)         // Optional. Maximum number of trades in the first feed.
+//  Symbol(string)         // The symbol of the candles to subscribe
+//  Period(PeriodType)     // A valid tick interval. A PeriodType
+//  Limit(int)             // Optional. Maximum number of trades in the first feed.
+//  Buffer(int)            // Optional. Size of the buffer of the feed channel. Not sent to the exchange
+//  Overflow(OverflowType) // Optional. Policy of the feed channel when full. Default is OverflowTypeBlock. Not sent to the exchange
 func (client *PublicClient) SubscribeToCandles(arguments ...args.Argument) (feedCh chan []models.Candle, err error) {
-	dataCh, err := client.doSubscription(methodSubscribeCandles, arguments, []string{"symbol", "period"})
+	return client.SubscribeToCandlesContext(context.Background(), arguments...)
+}
+
+// SubscribeToCandlesContext is SubscribeToCandles with a context bounding the subscription request,
+// instead of the timeout of the client.
+func (client *PublicClient) SubscribeToCandlesContext(ctx context.Context, arguments ...args.Argument) (feedCh chan []models.Candle, err error) {
+	feedCh, _, err = client.subscribeToCandles(ctx, arguments)
+	return feedCh, err
+}
+
+// CandlesSubscription is a subscription to candles, with its feed in C.
+type CandlesSubscription struct {
+	*Subscription
+	C <-chan []models.Candle
+}
+
+// CandlesSubscription is SubscribeToCandlesContext returning the handle of the
+// subscription.
+func (client *PublicClient) CandlesSubscription(ctx context.Context, arguments ...args.Argument) (*CandlesSubscription, error) {
+	feedCh, sub, err := client.subscribeToCandles(ctx, arguments)
 	if err != nil {
 		return nil, err
 	}
-	feedCh = make(chan []models.Candle)
-	go func() {
-		defer close(feedCh)
+	return &CandlesSubscription{Subscription: sub, C: feedCh}, nil
+}
+
+func (client *PublicClient) subscribeToCandles(ctx context.Context, arguments []args.Argument) (chan []models.Candle, *Subscription, error) {
+	sub, c, err := client.newSubscription(ctx, methodSubscribeCandles, methodUnsubscribeCandles, arguments, []string{"symbol", "period"})
+	if err != nil {
+		return nil, nil, err
+	}
+	feedCh := make(chan []models.Candle, sub.buffer)
+	go sub.run(c, feedCh, func(data []byte) error {
 		var resp struct {
 			Params struct {
 				Data []models.Candle
 			}
 		}
-		for data := range dataCh {
-			json.Unmarshal(data, &resp)
-			feedCh <- resp.Params.Data
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return err
 		}
-	}()
-	return feedCh, nil
+		sub.send(resp.Params.Data)
+		return nil
+	})
+	return feedCh, sub, nil
 }
 
 // UnsubscribeToCandles unsubscribes to the candles of a symbol at a given period.
-// It also closes the feedCh of the subscription
+// It also closes the feedCh of every consumer of the subscription; consumers
+// sharing it should leave with the Unsubscribe of their handles instead.
 //
 // https://api.exchange.cryptomarket.com/#subscribe-to-candles
 //
@@ -352,5 +545,11 @@ func (client *PublicClient) SubscribeToCandles(arguments ...args.Argument) (feed
 //  Symbol(string)     // The symbol of the candles to unsubscribe
 //  Period(PeriodType) // A valid tick interval. A PeriodType
 func (client *PublicClient) UnsubscribeToCandles(arguments ...args.Argument) error {
-	return client.doUnsubscription(methodUnsubscribeCandles, arguments, []string{"symbol", "period"})
+	return client.UnsubscribeToCandlesContext(context.Background(), arguments...)
+}
+
+// UnsubscribeToCandlesContext is UnsubscribeToCandles with a context bounding the unsubscription request,
+// instead of the timeout of the client.
+func (client *PublicClient) UnsubscribeToCandlesContext(ctx context.Context, arguments ...args.Argument) error {
+	return client.doUnsubscription(ctx, methodUnsubscribeCandles, arguments, []string{"symbol", "period"})
 }
@@ -0,0 +1,58 @@
+package websocket
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cryptomarket/cryptomarket-go/args"
+	"github.com/cryptomarket/cryptomarket-go/models"
+)
+
+func newFakePublicClient() (*PublicClient, *wsManager) {
+	manager := newFakeWSManager(func(notification wsNotification) []string {
+		return []string{fmt.Sprintf(`{"jsonrpc":"2.0","result":true,"id":%d}`, notification.ID)}
+	})
+	client := newPublicClient(manager)
+	go client.handle(manager.rcv)
+	return client, manager
+}
+
+func receiveOrderbook(t *testing.T, feedCh chan models.OrderBook) models.OrderBook {
+	select {
+	case book := <-feedCh:
+		return book
+	case <-time.After(time.Second):
+		t.Fatal("no orderbook received")
+	}
+	return models.OrderBook{}
+}
+
+func TestReducedOrderbookSubscription(t *testing.T) {
+	client, manager := newFakePublicClient()
+	defer client.Close()
+	feedCh, err := client.SubscribeToOrderbook(args.Symbol("EOSETH"), args.Depth(1), args.Grouping("0.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager.rcv <- []byte(`{"method":"snapshotOrderbook","params":{"symbol":"EOSETH","sequence":1,
+		"ask":[{"price":"1.01","size":"1"},{"price":"1.05","size":"1"},{"price":"1.2","size":"1"}],
+		"bid":[{"price":"0.99","size":"1"}]}}`)
+	book := receiveOrderbook(t, feedCh)
+	if len(book.Ask) != 1 || book.Ask[0].Price != "1.1" || book.Ask[0].Size != "2" {
+		t.Fatalf("wrong reduced ask side: %v", book.Ask)
+	}
+	// a change outside of the top level does not change the view
+	manager.rcv <- []byte(`{"method":"updateOrderbook","params":{"symbol":"EOSETH","sequence":2,
+		"ask":[{"price":"1.2","size":"5"}],"bid":[]}}`)
+	// a change inside the top bucket does
+	manager.rcv <- []byte(`{"method":"updateOrderbook","params":{"symbol":"EOSETH","sequence":3,
+		"ask":[{"price":"1.05","size":"0"}],"bid":[]}}`)
+	book = receiveOrderbook(t, feedCh)
+	if book.Ask[0].Size != "1" {
+		t.Fatalf("expected the view after the third update, got %v", book.Ask)
+	}
+	if _, err = client.SubscribeToOrderbook(args.Symbol("ETHBTC"), args.Grouping("-1")); err == nil {
+		t.Fatal("should fail with an invalid grouping")
+	}
+}
@@ -0,0 +1,134 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Notification is a message of a raw subscription feed.
+type Notification struct {
+	Method string
+	Params json.RawMessage
+}
+
+// Call sends a request of any method of the exchange, e.g. one not wrapped
+// by the client yet, and decodes the result of its response into result, if
+// not nil. The request is signed by the login of the connection, and bounded
+// by ctx or the timeout of the client. Methods unknown to the client need all
+// the capabilities.
+func (client *clientBase) Call(ctx context.Context, method string, params map[string]interface{}, result interface{}) error {
+	data, err := client.exchange(ctx, method, params)
+	if err != nil || result == nil {
+		return err
+	}
+	var resp struct {
+		Result json.RawMessage
+	}
+	json.Unmarshal(data, &resp)
+	if err = json.Unmarshal(resp.Result, result); err != nil {
+		return fmt.Errorf("CryptomarketSDKError: Failed to parse response data: %v", err)
+	}
+	return nil
+}
+
+// Subscribe subscribes to a feed of any method of the exchange, e.g. one not
+// wrapped by the client yet. The feed gets the notifications named after the
+// method: "ticker", "snapshotTicker" and "updateTicker" for "subscribeTicker",
+// and if params has a symbol, only the ones of that symbol.
+//
+// Methods wrapped by the client share the subscription to the exchange of
+// their typed subscriptions, and their raw feeds get the same notifications.
+func (client *clientBase) Subscribe(method string, params map[string]interface{}) (chan Notification, error) {
+	return client.SubscribeContext(context.Background(), method, params)
+}
+
+// SubscribeContext is Subscribe with a context bounding the subscription
+// request, instead of the timeout of the client.
+func (client *clientBase) SubscribeContext(ctx context.Context, method string, params map[string]interface{}) (chan Notification, error) {
+	if err := client.checkCapability(method); err != nil {
+		return nil, err
+	}
+	c, err := client.subscribe(ctx, method, client.rawFeedKey(method, params), params, true)
+	if err != nil {
+		return nil, err
+	}
+	feedCh := make(chan Notification)
+	go func() {
+		defer close(feedCh)
+		for {
+			select {
+			case data := <-c.ch:
+				var notification Notification
+				json.Unmarshal(data, &notification)
+				select {
+				case feedCh <- notification:
+				case <-c.done:
+					return
+				}
+			case <-c.done:
+				return
+			}
+		}
+	}()
+	return feedCh, nil
+}
+
+// Unsubscribe ends a raw subscription, closing its feeds. method is the
+// unsubscription method, e.g. "unsubscribeTicker". The exchange is only
+// unsubscribed if no typed subscription of the client shares the feed.
+func (client *clientBase) Unsubscribe(method string, params map[string]interface{}) error {
+	return client.UnsubscribeContext(context.Background(), method, params)
+}
+
+// UnsubscribeContext is Unsubscribe with a context bounding the
+// unsubscription request, instead of the timeout of the client.
+func (client *clientBase) UnsubscribeContext(ctx context.Context, method string, params map[string]interface{}) error {
+	if !strings.HasPrefix(method, "unsubscribe") {
+		return fmt.Errorf("CryptomarketSDKError: not an unsubscription method: %q", method)
+	}
+	if err := client.checkCapability(method); err != nil {
+		return err
+	}
+	if !client.wsManager.isOpen {
+		return errConnectionClosed
+	}
+	return client.chanCache.leaveRaw(client.rawFeedKey(method, params), func() error {
+		return client.exchangeUnsubscription(ctx, method, params)
+	})
+}
+
+// rawName returns the name of the feed of a method, the same for the
+// subscription, the unsubscription and the notifications of a feed, e.g.
+// "ticker" for "subscribeTicker" and "trade" for "updateTrades".
+func rawName(method string) string {
+	name := strings.ToLower(method)
+	for _, prefix := range []string{"unsubscribe", "subscribe", "snapshot", "update"} {
+		if strings.HasPrefix(name, prefix) {
+			name = name[len(prefix):]
+			break
+		}
+	}
+	return strings.TrimSuffix(name, "s")
+}
+
+// rawFeedKey returns the key of the feed of a raw subscription. Methods wrapped
+// by the client use the key of their typed subscriptions.
+func (client *clientBase) rawFeedKey(method string, params map[string]interface{}) string {
+	if _, wrapped := methodCapabilities[method]; wrapped {
+		if key, ok := client.subscriptionKeysFunc(method, params); ok {
+			return key
+		}
+	}
+	return rawKey(method, params)
+}
+
+func rawKey(method string, params map[string]interface{}) string {
+	symbol, _ := params["symbol"].(string)
+	return rawKeyOf(method, symbol)
+}
+
+func rawKeyOf(method, symbol string) string {
+	return "raw:" + rawName(method) + ":" + strings.ToUpper(symbol)
+}
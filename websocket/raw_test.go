@@ -0,0 +1,181 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cryptomarket/cryptomarket-go/args"
+	"github.com/cryptomarket/cryptomarket-go/auth"
+)
+
+func TestCall(t *testing.T) {
+	manager := newFakeWSManager(func(notification wsNotification) []string {
+		if notification.Method == "getFutures" && notification.Params["symbol"] == "EOSETH" {
+			return []string{fmt.Sprintf(`{"jsonrpc":"2.0","result":{"symbol":"EOSETH","rate":"0.01"},"id":%d}`, notification.ID)}
+		}
+		return []string{fmt.Sprintf(`{"jsonrpc":"2.0","error":{"code":2001,"message":"Method not found"},"id":%d}`, notification.ID)}
+	})
+	client := newPublicClient(manager)
+	go client.handle(manager.rcv)
+	defer client.Close()
+
+	var result struct {
+		Rate string
+	}
+	if err := client.Call(context.Background(), "getFutures", map[string]interface{}{"symbol": "EOSETH"}, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Rate != "0.01" {
+		t.Fatalf("wrong result: %+v", result)
+	}
+	if err := client.Call(context.Background(), "getNothing", nil, nil); err == nil {
+		t.Fatal("expected an api error")
+	}
+
+	restricted := newTradingClient(manager)
+	restricted.apply([]Option{WithCapabilities(auth.ReadOnly)})
+	var capabilityErr *auth.CapabilityError
+	if err := restricted.Call(context.Background(), "newMarginOrder", nil, nil); !errors.As(err, &capabilityErr) {
+		t.Fatalf("expected a capability error, got %v", err)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	manager := newFakeWSManager(func(notification wsNotification) []string {
+		return []string{fmt.Sprintf(`{"jsonrpc":"2.0","result":true,"id":%d}`, notification.ID)}
+	})
+	client := newPublicClient(manager)
+	go client.handle(manager.rcv)
+	defer client.Close()
+
+	feedCh, err := client.Subscribe("subscribeFutures", map[string]interface{}{"symbol": "EOSETH"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager.rcv <- []byte(`{"jsonrpc":"2.0","method":"snapshotFutures","params":{"symbol":"ETHBTC","rate":"0.02"}}`)
+	manager.rcv <- []byte(`{"jsonrpc":"2.0","method":"snapshotFutures","params":{"symbol":"EOSETH","rate":"0.01"}}`)
+	manager.rcv <- []byte(`{"jsonrpc":"2.0","method":"updateFutures","params":{"symbol":"EOSETH","rate":"0.03"}}`)
+	for _, method := range []string{"snapshotFutures", "updateFutures"} {
+		select {
+		case notification := <-feedCh:
+			if notification.Method != method {
+				t.Fatalf("got %v, expected %v", notification.Method, method)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("no notification")
+		}
+	}
+	if err = client.Unsubscribe("unsubscribeFutures", map[string]interface{}{"symbol": "EOSETH"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-feedCh; ok {
+		t.Fatal("the feed was not closed")
+	}
+}
+
+func TestSubscribeSharesTypedFeed(t *testing.T) {
+	var mutex sync.Mutex
+	requests := make(map[string]int)
+	manager := newFakeWSManager(func(notification wsNotification) []string {
+		mutex.Lock()
+		requests[notification.Method]++
+		mutex.Unlock()
+		return []string{fmt.Sprintf(`{"jsonrpc":"2.0","result":true,"id":%d}`, notification.ID)}
+	})
+	client := newPublicClient(manager)
+	go client.handle(manager.rcv)
+	defer client.Close()
+	count := func(method string) int {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return requests[method]
+	}
+
+	sub, err := client.TickerSubscription(context.Background(), args.Symbol("EOSETH"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	feedCh, err := client.Subscribe(methodSubscribeTicker, map[string]interface{}{"symbol": "EOSETH"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count(methodSubscribeTicker) != 1 {
+		t.Fatalf("expected a single subscription to the exchange, got %v", count(methodSubscribeTicker))
+	}
+	manager.rcv <- []byte(`{"jsonrpc":"2.0","method":"ticker","params":{"symbol":"EOSETH","last":"0.01"}}`)
+	select {
+	case notification := <-feedCh:
+		if notification.Method != "ticker" {
+			t.Fatalf("wrong notification: %+v", notification)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no raw notification")
+	}
+	select {
+	case <-sub.C:
+	case <-time.After(time.Second):
+		t.Fatal("no ticker")
+	}
+
+	// the raw unsubscription leaves the typed subscription alone
+	if err = client.Unsubscribe(methodUnsubcribeTicker, map[string]interface{}{"symbol": "EOSETH"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-feedCh; ok {
+		t.Fatal("the raw feed was not closed")
+	}
+	if count(methodUnsubcribeTicker) != 0 {
+		t.Fatal("unsubscribed from the exchange with a typed subscription left")
+	}
+	manager.rcv <- []byte(`{"jsonrpc":"2.0","method":"ticker","params":{"symbol":"EOSETH","last":"0.02"}}`)
+	select {
+	case ticker := <-sub.C:
+		if ticker.Last != "0.02" {
+			t.Fatalf("wrong ticker: %+v", ticker)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the typed feed ended with the raw one")
+	}
+	if err = sub.Unsubscribe(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if count(methodUnsubcribeTicker) != 1 {
+		t.Fatalf("expected a single unsubscription from the exchange, got %v", count(methodUnsubcribeTicker))
+	}
+}
+
+func TestUnsubscribeChecksMethod(t *testing.T) {
+	var mutex sync.Mutex
+	sent := make([]string, 0)
+	manager := newFakeWSManager(func(notification wsNotification) []string {
+		mutex.Lock()
+		sent = append(sent, notification.Method)
+		mutex.Unlock()
+		return []string{fmt.Sprintf(`{"jsonrpc":"2.0","result":true,"id":%d}`, notification.ID)}
+	})
+	client := newTradingClient(manager)
+	client.apply([]Option{WithCapabilities(auth.ReadOnly)})
+	go client.handle(manager.rcv)
+	defer client.Close()
+
+	params := map[string]interface{}{"symbol": "EOSETH", "side": "buy", "quantity": "1"}
+	if err := client.Unsubscribe("newOrder", params); err == nil {
+		t.Fatal("expected an error for a method that is not an unsubscription")
+	}
+	var capabilityErr *auth.CapabilityError
+	if err := client.Unsubscribe("unsubscribeFutures", params); !errors.As(err, &capabilityErr) {
+		t.Fatalf("expected a capability error, got %v", err)
+	}
+	if err := client.Unsubscribe(methodUnsubcribeTicker, map[string]interface{}{"symbol": "EOSETH"}); err != nil {
+		t.Fatal(err)
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(sent) != 1 || sent[0] != methodUnsubcribeTicker {
+		t.Fatalf("only the ticker unsubscription should be sent, got %v", sent)
+	}
+}
@@ -0,0 +1,196 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/cryptomarket/cryptomarket-go/args"
+	"github.com/cryptomarket/cryptomarket-go/metrics"
+)
+
+// ErrFeedClosed is reported by a subscription whose feed ended without being
+// unsubscribed, e.g. by the close of the connection.
+var ErrFeedClosed = errors.New("CryptomarketSDKError: subscription feed closed")
+
+// SubscriptionStats are the counters of a subscription.
+type SubscriptionStats struct {
+	Messages   int64     // messages received
+	Errors     int64     // messages that failed to decode
+	Dropped    int64     // values dropped by the overflow policy
+	LastUpdate time.Time // reception of the last message
+}
+
+// Subscription is the handle of a subscription feed. The typed
+// subscriptions, like TickerSubscription, embed it along with the channel
+// of their feed.
+type Subscription struct {
+	errCh        chan error
+	done         chan struct{}
+	doneOnce     sync.Once
+	unsubscribe  func(ctx context.Context) error
+	mutex        sync.Mutex
+	unsubscribed bool
+	stats        SubscriptionStats
+	buffer       int               // of the feed channel
+	overflow     args.OverflowType // of the feed channel
+	feed         reflect.Value     // the feed channel, typed by each subscription
+	metrics      metrics.Recorder
+	labels       metrics.Labels
+}
+
+// newSubscription subscribes with method, returning the handle of the feed
+// and its consumer, to be passed to run. The handle unsubscribes from the
+// exchange with unsubscribeMethod once the feed has no other consumer, and
+// feeds without unsubscribeMethod are only left. The Buffer and Overflow
+// arguments shape the feed channel and are not sent to the exchange.
+func (client *clientBase) newSubscription(ctx context.Context, method, unsubscribeMethod string, arguments []args.Argument, requiredArguments []string) (*Subscription, *consumer, error) {
+	arguments, local := splitLocalArguments(arguments)
+	buffer, _ := local["buffer"].(int)
+	overflow, _ := local["overflow"].(args.OverflowType)
+	switch overflow {
+	case "":
+		overflow = args.OverflowTypeBlock
+	case args.OverflowTypeConflate:
+		buffer = 1
+	case args.OverflowTypeBlock, args.OverflowTypeDropOldest, args.OverflowTypeDropNewest:
+	default:
+		return nil, nil, fmt.Errorf("CryptomarketSDKError: invalid overflow: %v", overflow)
+	}
+	if buffer < 0 {
+		return nil, nil, fmt.Errorf("CryptomarketSDKError: invalid buffer: %v", buffer)
+	}
+	c, err := client.doSubscription(ctx, method, arguments, requiredArguments)
+	if err != nil {
+		return nil, nil, err
+	}
+	params, _ := args.BuildParams(arguments)
+	sub := &Subscription{
+		errCh: make(chan error, 16),
+		done:  make(chan struct{}),
+		unsubscribe: func(ctx context.Context) error {
+			return client.unsubscribe(ctx, c, unsubscribeMethod, params)
+		},
+		buffer:   buffer,
+		overflow: overflow,
+		metrics:  client.wsManager.metrics,
+		labels:   metrics.Labels{"path": client.wsManager.streamPath, "feed": c.key},
+	}
+	return sub, c, nil
+}
+
+// run passes the messages of the consumer to deliver until the feed ends,
+// and then closes feedCh, a channel of sub.buffer made by the caller.
+// deliver returns the decoding errors, and sends the values of the feed
+// with send.
+func (sub *Subscription) run(c *consumer, feedCh interface{}, deliver func(data []byte) error) {
+	sub.feed = reflect.ValueOf(feedCh)
+	defer func() {
+		sub.feed.Close()
+		sub.mutex.Lock()
+		unsubscribed := sub.unsubscribed
+		sub.mutex.Unlock()
+		if !unsubscribed {
+			sub.report(ErrFeedClosed)
+		}
+		sub.end()
+	}()
+	for {
+		var data []byte
+		select {
+		case data = <-c.ch:
+		case <-c.done:
+			return
+		}
+		sub.mutex.Lock()
+		sub.stats.Messages++
+		sub.stats.LastUpdate = time.Now()
+		sub.mutex.Unlock()
+		if err := deliver(data); err != nil {
+			sub.mutex.Lock()
+			sub.stats.Errors++
+			sub.mutex.Unlock()
+			sub.report(fmt.Errorf("CryptomarketSDKError: invalid feed message: %v", err))
+		}
+	}
+}
+
+// send sends a value to the feed channel, following the overflow policy.
+// Blocking sends give up when the subscription ends.
+func (sub *Subscription) send(value interface{}) {
+	val := reflect.ValueOf(value)
+	switch sub.overflow {
+	case args.OverflowTypeDropNewest:
+		if !sub.feed.TrySend(val) {
+			sub.drop()
+		}
+	case args.OverflowTypeDropOldest, args.OverflowTypeConflate:
+		for !sub.feed.TrySend(val) {
+			sub.drop()
+			if _, ok := sub.feed.TryRecv(); !ok {
+				// an unbuffered feed has nothing older to drop
+				break
+			}
+		}
+	default:
+		reflect.Select([]reflect.SelectCase{
+			{Dir: reflect.SelectSend, Chan: sub.feed, Send: val},
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(sub.done)},
+		})
+	}
+}
+
+func (sub *Subscription) drop() {
+	sub.mutex.Lock()
+	sub.stats.Dropped++
+	sub.mutex.Unlock()
+	sub.metrics.Add(metrics.WSFeedDrops, sub.labels, 1)
+}
+
+// report sends an error to the Err channel, dropping it if the channel is full.
+func (sub *Subscription) report(err error) {
+	select {
+	case sub.errCh <- err:
+	default:
+	}
+}
+
+func (sub *Subscription) end() {
+	sub.doneOnce.Do(func() { close(sub.done) })
+}
+
+// Err returns a channel of the errors of the feed: messages that failed to
+// decode, and ErrFeedClosed if the feed ends without being unsubscribed.
+// Errors are dropped while the buffer of the channel is full.
+func (sub *Subscription) Err() <-chan error {
+	return sub.errCh
+}
+
+// Done returns a channel closed when the feed ends.
+func (sub *Subscription) Done() <-chan struct{} {
+	return sub.done
+}
+
+// Unsubscribe ends the feed, closing its channel, and unsubscribes from the
+// exchange if no other consumer shares the subscription.
+func (sub *Subscription) Unsubscribe(ctx context.Context) error {
+	sub.mutex.Lock()
+	if sub.unsubscribed {
+		sub.mutex.Unlock()
+		return nil
+	}
+	sub.unsubscribed = true
+	sub.mutex.Unlock()
+	sub.end()
+	return sub.unsubscribe(ctx)
+}
+
+// Stats returns the counters of the subscription.
+func (sub *Subscription) Stats() SubscriptionStats {
+	sub.mutex.Lock()
+	defer sub.mutex.Unlock()
+	return sub.stats
+}
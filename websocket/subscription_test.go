@@ -0,0 +1,375 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cryptomarket/cryptomarket-go/args"
+	"github.com/cryptomarket/cryptomarket-go/metrics"
+	"github.com/cryptomarket/cryptomarket-go/models"
+)
+
+func TestSubscriptionHandle(t *testing.T) {
+	var unsubscribed bool
+	manager := newFakeWSManager(func(notification wsNotification) []string {
+		if notification.Method == methodUnsubcribeTicker {
+			unsubscribed = true
+		}
+		return []string{fmt.Sprintf(`{"jsonrpc":"2.0","result":true,"id":%d}`, notification.ID)}
+	})
+	client := newPublicClient(manager)
+	go client.handle(manager.rcv)
+	defer client.Close()
+
+	sub, err := client.TickerSubscription(context.Background(), args.Symbol("EOSETH"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager.rcv <- []byte(`{"jsonrpc":"2.0","method":"ticker","params":{"symbol":"EOSETH","last":0.01}}`)
+	manager.rcv <- []byte(`{"jsonrpc":"2.0","method":"ticker","params":{"symbol":"EOSETH","last":"0.02"}}`)
+	select {
+	case err := <-sub.Err():
+		if err == nil {
+			t.Fatal("expected a decoding error")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no decoding error")
+	}
+	select {
+	case ticker := <-sub.C:
+		if ticker.Last != "0.02" {
+			t.Fatalf("wrong ticker: %+v", ticker)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no ticker")
+	}
+	stats := sub.Stats()
+	if stats.Messages != 2 || stats.Errors != 1 || stats.LastUpdate.IsZero() {
+		t.Fatalf("wrong stats: %+v", stats)
+	}
+
+	if err = sub.Unsubscribe(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !unsubscribed {
+		t.Fatal("not unsubscribed from the exchange")
+	}
+	select {
+	case <-sub.Done():
+	case <-time.After(time.Second):
+		t.Fatal("the subscription is not done")
+	}
+	select {
+	case _, ok := <-sub.C:
+		if ok {
+			t.Fatal("the feed was not closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the feed was not closed")
+	}
+	select {
+	case err := <-sub.Err():
+		t.Fatalf("unexpected error: %v", err)
+	default:
+	}
+}
+
+func TestSubscriptionFeedClosed(t *testing.T) {
+	manager := newFakeWSManager(func(notification wsNotification) []string {
+		return []string{fmt.Sprintf(`{"jsonrpc":"2.0","result":true,"id":%d}`, notification.ID)}
+	})
+	client := newTradingClient(manager)
+	go client.handle(manager.rcv)
+
+	sub, err := client.ReportsSubscription(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager.rcv <- []byte(`{"jsonrpc":"2.0","method":"activeOrders","params":[{"clientOrderId":"a"},{"clientOrderId":"b"}]}`)
+	for _, id := range []string{"a", "b"} {
+		if report := <-sub.C; report.ClientOrderID != id {
+			t.Fatalf("got report %v, expected %v", report.ClientOrderID, id)
+		}
+	}
+	client.Close()
+	select {
+	case err := <-sub.Err():
+		if err != ErrFeedClosed {
+			t.Fatalf("got %v, expected %v", err, ErrFeedClosed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the close of the feed was not reported")
+	}
+	<-sub.Done()
+}
+
+func TestSubscriptionFanOut(t *testing.T) {
+	var mutex sync.Mutex
+	calls := make(map[string]int)
+	manager := newFakeWSManager(func(notification wsNotification) []string {
+		mutex.Lock()
+		calls[notification.Method]++
+		mutex.Unlock()
+		return []string{fmt.Sprintf(`{"jsonrpc":"2.0","result":true,"id":%d}`, notification.ID)}
+	})
+	client := newPublicClient(manager)
+	go client.handle(manager.rcv)
+	defer client.Close()
+	callsOf := func(method string) int {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return calls[method]
+	}
+
+	first, err := client.TradesSubscription(context.Background(), args.Symbol("EOSETH"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager.rcv <- []byte(`{"jsonrpc":"2.0","method":"snapshotTrades","params":{"symbol":"EOSETH","data":[{"id":1}]}}`)
+	if trades := <-first.C; len(trades) != 1 || trades[0].ID != 1 {
+		t.Fatalf("wrong snapshot: %+v", trades)
+	}
+	// the second consumer gets the snapshot of the first
+	second, err := client.TradesSubscription(context.Background(), args.Symbol("EOSETH"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trades := <-second.C; len(trades) != 1 || trades[0].ID != 1 {
+		t.Fatalf("wrong snapshot replay: %+v", trades)
+	}
+	plain, err := client.SubscribeToTrades(args.Symbol("EOSETH"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-plain
+	if calls := callsOf(methodSubscribeTrades); calls != 1 {
+		t.Fatalf("subscribed %v times", calls)
+	}
+
+	manager.rcv <- []byte(`{"jsonrpc":"2.0","method":"updateTrades","params":{"symbol":"EOSETH","data":[{"id":2}]}}`)
+	for _, feedCh := range []<-chan []models.PublicTrade{first.C, second.C, plain} {
+		if trades := <-feedCh; len(trades) != 1 || trades[0].ID != 2 {
+			t.Fatalf("wrong update: %+v", trades)
+		}
+	}
+
+	// the consumers leave independently, the last one unsubscribes
+	if err = first.Unsubscribe(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	manager.rcv <- []byte(`{"jsonrpc":"2.0","method":"updateTrades","params":{"symbol":"EOSETH","data":[{"id":3}]}}`)
+	if trades := <-second.C; trades[0].ID != 3 {
+		t.Fatalf("wrong update: %+v", trades)
+	}
+	<-plain
+	if calls := callsOf(methodUnsubscribeTrades); calls != 0 {
+		t.Fatalf("unsubscribed with consumers left")
+	}
+	if err = second.Unsubscribe(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if calls := callsOf(methodUnsubscribeTrades); calls != 0 {
+		t.Fatalf("unsubscribed with consumers left")
+	}
+	// the plain unsubscription ends the feeds left
+	if err = client.UnsubscribeToTrades(args.Symbol("EOSETH")); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-plain; ok {
+		t.Fatal("the feed was not closed")
+	}
+	if calls := callsOf(methodUnsubscribeTrades); calls != 1 {
+		t.Fatalf("unsubscribed %v times", calls)
+	}
+
+	tickers := make([]*TickerSubscription, 2)
+	for idx := range tickers {
+		if tickers[idx], err = client.TickerSubscription(context.Background(), args.Symbol("EOSETH")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for idx, sub := range tickers {
+		if err = sub.Unsubscribe(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if calls := callsOf(methodUnsubcribeTicker); calls != idx {
+			t.Fatalf("unsubscribed %v times after %v consumers left", calls, idx+1)
+		}
+	}
+}
+
+func TestSubscriptionOverflow(t *testing.T) {
+	manager := newFakeWSManager(func(notification wsNotification) []string {
+		return []string{fmt.Sprintf(`{"jsonrpc":"2.0","result":true,"id":%d}`, notification.ID)}
+	})
+	registry := metrics.NewRegistry()
+	client := newPublicClient(manager)
+	client.apply([]Option{WithMetrics(registry)})
+	go client.handle(manager.rcv)
+	defer client.Close()
+
+	if _, err := client.TickerSubscription(context.Background(), args.Symbol("EOSETH"), args.Overflow("dropAll")); err == nil {
+		t.Fatal("expected an invalid overflow error")
+	}
+	tests := []struct {
+		symbol   string
+		buffer   int
+		overflow args.OverflowType
+		expected []string
+	}{
+		{"EOSETH", 2, args.OverflowTypeDropNewest, []string{"1", "2"}},
+		{"ETHBTC", 2, args.OverflowTypeDropOldest, []string{"4", "5"}},
+		{"BTCUSD", 0, args.OverflowTypeConflate, []string{"5"}},
+	}
+	for _, test := range tests {
+		sub, err := client.TickerSubscription(context.Background(), args.Symbol(test.symbol), args.Buffer(test.buffer), args.Overflow(test.overflow))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the consumer falls behind without stalling the connection
+		for idx := 1; idx <= 5; idx++ {
+			manager.rcv <- []byte(fmt.Sprintf(`{"jsonrpc":"2.0","method":"ticker","params":{"symbol":"%s","last":"%d"}}`, test.symbol, idx))
+		}
+		if err = client.Call(context.Background(), "getCurrencies", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+		// the error of a last malformed message follows the delivery of the others
+		manager.rcv <- []byte(fmt.Sprintf(`{"jsonrpc":"2.0","method":"ticker","params":{"symbol":"%s","last":6}}`, test.symbol))
+		select {
+		case <-sub.Err():
+		case <-time.After(time.Second):
+			t.Fatalf("%v: the feed is stalled", test.overflow)
+		}
+		for _, last := range test.expected {
+			if ticker := <-sub.C; ticker.Last != last {
+				t.Fatalf("%v: got ticker %v, expected %v", test.overflow, ticker.Last, last)
+			}
+		}
+		select {
+		case ticker := <-sub.C:
+			t.Fatalf("%v: unexpected ticker %v", test.overflow, ticker.Last)
+		default:
+		}
+		dropped := int64(5 - len(test.expected))
+		if stats := sub.Stats(); stats.Dropped != dropped {
+			t.Fatalf("%v: dropped %v, expected %v", test.overflow, stats.Dropped, dropped)
+		}
+		labels := metrics.Labels{"path": "", "feed": sub.labels["feed"]}
+		if value := registry.Value(metrics.WSFeedDrops, labels); value != float64(dropped) {
+			t.Fatalf("%v: drops metric is %v, expected %v", test.overflow, value, dropped)
+		}
+	}
+}
+
+func TestSubscriptionConcurrency(t *testing.T) {
+	var mutex sync.Mutex
+	var methods []string
+	gates := make(map[string]chan bool) // of the responses, true for a success
+	for _, method := range []string{methodSubscribeTicker, methodUnsubcribeTicker} {
+		gates[method] = make(chan bool)
+	}
+	var manager *wsManager
+	manager = newFakeWSManager(func(notification wsNotification) []string {
+		mutex.Lock()
+		methods = append(methods, notification.Method)
+		mutex.Unlock()
+		go func() {
+			if ok := <-gates[notification.Method]; !ok {
+				manager.rcv <- []byte(fmt.Sprintf(`{"jsonrpc":"2.0","error":{"code":2002,"message":"Currency not found"},"id":%d}`, notification.ID))
+				return
+			}
+			manager.rcv <- []byte(fmt.Sprintf(`{"jsonrpc":"2.0","result":true,"id":%d}`, notification.ID))
+		}()
+		return nil
+	})
+	client := newPublicClient(manager)
+	go client.handle(manager.rcv)
+	defer client.Close()
+	sent := func() string {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return strings.Join(methods, ",")
+	}
+	key := client.buildKey(methodSubscribeTicker, map[string]interface{}{"symbol": "EOSETH"})
+	consumers := func() int {
+		client.chanCache.feedsLock.Lock()
+		defer client.chanCache.feedsLock.Unlock()
+		if f, ok := client.chanCache.feeds[key]; ok {
+			return len(f.consumers)
+		}
+		return 0
+	}
+	type result struct {
+		sub *TickerSubscription
+		err error
+	}
+	subscribe := func() chan result {
+		resultCh := make(chan result, 1)
+		go func() {
+			sub, err := client.TickerSubscription(context.Background(), args.Symbol("EOSETH"))
+			resultCh <- result{sub, err}
+		}()
+		return resultCh
+	}
+
+	// the consumers joining a pending subscription fail with it
+	first, second := subscribe(), subscribe()
+	if !waitFor(func() bool { return consumers() == 2 }) {
+		t.Fatal("the consumers did not join")
+	}
+	gates[methodSubscribeTicker] <- false
+	for _, resultCh := range []chan result{first, second} {
+		if res := <-resultCh; res.err == nil {
+			t.Fatal("expected the error of the subscription")
+		}
+	}
+	if consumers() != 0 || sent() != methodSubscribeTicker {
+		t.Fatalf("wrong state: %v consumers, sent %v", consumers(), sent())
+	}
+
+	// and succeed with it
+	first, second = subscribe(), subscribe()
+	if !waitFor(func() bool { return consumers() == 2 }) {
+		t.Fatal("the consumers did not join")
+	}
+	gates[methodSubscribeTicker] <- true
+	subs := make([]*TickerSubscription, 0)
+	for _, resultCh := range []chan result{first, second} {
+		res := <-resultCh
+		if res.err != nil {
+			t.Fatal(res.err)
+		}
+		subs = append(subs, res.sub)
+	}
+
+	// a subscription waits for the unsubscription of the last consumer
+	if err := subs[0].Unsubscribe(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	unsubscribed := make(chan error, 1)
+	go func() { unsubscribed <- subs[1].Unsubscribe(context.Background()) }()
+	if !waitFor(func() bool { return strings.HasSuffix(sent(), methodUnsubcribeTicker) }) {
+		t.Fatalf("not unsubscribed: sent %v", sent())
+	}
+	third := subscribe()
+	time.Sleep(20 * time.Millisecond)
+	gates[methodUnsubcribeTicker] <- true
+	if err := <-unsubscribed; err != nil {
+		t.Fatal(err)
+	}
+	if !waitFor(func() bool { return strings.HasSuffix(sent(), methodUnsubcribeTicker+","+methodSubscribeTicker) }) {
+		t.Fatalf("wrong order: sent %v", sent())
+	}
+	gates[methodSubscribeTicker] <- true
+	if res := <-third; res.err != nil || consumers() != 1 {
+		t.Fatalf("wrong resubscription: %v, %v consumers", res.err, consumers())
+	}
+	expected := strings.Join([]string{methodSubscribeTicker, methodSubscribeTicker, methodUnsubcribeTicker, methodSubscribeTicker}, ",")
+	if sent() != expected {
+		t.Fatalf("sent %v, expected %v", sent(), expected)
+	}
+}
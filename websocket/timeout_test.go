@@ -0,0 +1,65 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cryptomarket/cryptomarket-go/args"
+	"github.com/cryptomarket/cryptomarket-go/auth"
+)
+
+func TestTimeout(t *testing.T) {
+	unanswered := make(chan int64, 3)
+	manager := newFakeWSManager(func(notification wsNotification) []string {
+		unanswered <- notification.ID
+		return nil
+	})
+	client := newPublicClient(manager)
+	client.apply([]Option{WithTimeout(20 * time.Millisecond)})
+	go client.handle(manager.rcv)
+	defer client.Close()
+
+	if _, err := client.SubscribeToTicker(args.Symbol("EOSETH")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+	client.chanCache.feedsLock.Lock()
+	_, ok := client.chanCache.feeds[client.buildKey(methodSubscribeTicker, map[string]interface{}{"symbol": "EOSETH"})]
+	client.chanCache.feedsLock.Unlock()
+	if ok {
+		t.Fatal("the failed subscription was kept")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-unanswered
+		cancel()
+	}()
+	if err := client.UnsubscribeToTickerContext(ctx, args.Symbol("EOSETH")); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a cancellation, got %v", err)
+	}
+	if pending := atomic.LoadInt64(&client.chanCache.pending); pending != 0 {
+		t.Fatalf("expected no pending requests, got %v", pending)
+	}
+
+	// a late response is dropped
+	manager.rcv <- []byte(fmt.Sprintf(`{"jsonrpc":"2.0","result":true,"id":%d}`, <-unanswered))
+}
+
+func TestLoginTimeout(t *testing.T) {
+	manager := newFakeWSManager(func(notification wsNotification) []string {
+		return nil
+	})
+	client := newTradingClient(manager)
+	go client.handle(manager.rcv)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := client.SetSignerContext(ctx, auth.HS256("key", "secret")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+}
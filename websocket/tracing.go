@@ -0,0 +1,47 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/cryptomarket/cryptomarket-go/audit"
+	"github.com/cryptomarket/cryptomarket-go/tracing"
+)
+
+// WithTracer starts a span per request of the client, from its sending to
+// its response, as a child of the span in the context of the request. The
+// span carries the json-rpc id, the symbol and the client order id of the
+// request, the id of its result, and the error code of the exchange if it
+// fails. Subscriptions have no parent span.
+func WithTracer(tracer tracing.Tracer) Option {
+	return func(client *clientBase) {
+		client.wsManager.tracer = tracer
+	}
+}
+
+func (client *clientBase) startSpan(ctx context.Context, method string, id int64, params map[string]interface{}) tracing.Span {
+	_, span := client.wsManager.tracer.Start(ctx, "cryptomarket.ws "+method)
+	span.SetAttribute(tracing.AttributeMethod, method)
+	span.SetAttribute(tracing.AttributeRequestID, id)
+	tracing.SetParams(span, params)
+	return span
+}
+
+// endSpan ends the span of a request with its response, if any.
+func endSpan(span tracing.Span, response []byte, err error) {
+	defer span.End()
+	var resp struct {
+		Error struct {
+			Code interface{}
+		}
+	}
+	json.Unmarshal(response, &resp)
+	if resp.Error.Code != nil {
+		span.SetAttribute(tracing.AttributeErrorCode, resp.Error.Code)
+	} else if id := audit.ResultID(response); id != "" && err == nil {
+		span.SetAttribute(tracing.AttributeResultID, id)
+	}
+	if err != nil {
+		span.RecordError(err)
+	}
+}
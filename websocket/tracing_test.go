@@ -0,0 +1,72 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/cryptomarket/cryptomarket-go/args"
+	"github.com/cryptomarket/cryptomarket-go/tracing"
+)
+
+type spanKey struct{}
+
+type fakeSpan struct {
+	name       string
+	parent     *fakeSpan
+	attributes map[string]interface{}
+	err        error
+	ended      bool
+}
+
+func (span *fakeSpan) SetAttribute(key string, value interface{}) { span.attributes[key] = value }
+func (span *fakeSpan) RecordError(err error)                      { span.err = err }
+func (span *fakeSpan) End()                                       { span.ended = true }
+
+type fakeTracer struct {
+	mutex sync.Mutex
+	spans []*fakeSpan
+}
+
+func (tracer *fakeTracer) Start(ctx context.Context, name string) (context.Context, tracing.Span) {
+	parent, _ := ctx.Value(spanKey{}).(*fakeSpan)
+	span := &fakeSpan{name: name, parent: parent, attributes: make(map[string]interface{})}
+	tracer.mutex.Lock()
+	tracer.spans = append(tracer.spans, span)
+	tracer.mutex.Unlock()
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func TestTracing(t *testing.T) {
+	manager := newFakeWSManager(func(notification wsNotification) []string {
+		if notification.Method == methodCancelOrder {
+			return []string{fmt.Sprintf(`{"jsonrpc":"2.0","error":{"code":20002,"message":"Order not found"},"id":%d}`, notification.ID)}
+		}
+		return []string{fmt.Sprintf(`{"jsonrpc":"2.0","result":{"id":1,"clientOrderId":"abc"},"id":%d}`, notification.ID)}
+	})
+	tracer := &fakeTracer{}
+	client := newTradingClient(manager)
+	client.apply([]Option{WithTracer(tracer)})
+	go client.handle(manager.rcv)
+	defer client.Close()
+
+	parent := &fakeSpan{name: "handler"}
+	ctx := context.WithValue(context.Background(), spanKey{}, parent)
+	client.CreateOrder(ctx, args.ClientOrderID("abc"), args.Symbol("EOSETH"), args.Side(args.SideTypeSell), args.Quantity("0.01"))
+	client.CancelOrder(ctx, args.ClientOrderID("xyz"))
+
+	if len(tracer.spans) != 2 {
+		t.Fatalf("expected 2 spans, got %v", len(tracer.spans))
+	}
+	created, canceled := tracer.spans[0], tracer.spans[1]
+	if created.name != "cryptomarket.ws newOrder" || created.parent != parent || !created.ended || created.attributes[tracing.AttributeRequestID] == nil {
+		t.Fatalf("wrong span: %+v", created)
+	}
+	if created.attributes[tracing.AttributeSymbol] != "EOSETH" || created.attributes[tracing.AttributeResultID] != "abc" {
+		t.Fatalf("wrong attributes: %v", created.attributes)
+	}
+	if canceled.attributes[tracing.AttributeErrorCode] != float64(20002) || canceled.err == nil || canceled.attributes[tracing.AttributeClientOrderID] != "xyz" {
+		t.Fatalf("wrong failed span: %+v", canceled)
+	}
+}
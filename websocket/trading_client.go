@@ -6,10 +6,12 @@ import (
 	"fmt"
 
 	"github.com/cryptomarket/cryptomarket-go/args"
+	"github.com/cryptomarket/cryptomarket-go/auth"
+	"github.com/cryptomarket/cryptomarket-go/credentials"
 	"github.com/cryptomarket/cryptomarket-go/models"
 )
 
-// TradingClient connects via websocket to cryptomarket to enable the user to manage orders. uses SHA256 as auth method and authenticates automatically.
+// TradingClient connects via websocket to cryptomarket to enable the user to manage orders. authenticates automatically, with HS256 unless another signer is given.
 type TradingClient struct {
 	clientBase
 }
@@ -17,7 +19,58 @@ type TradingClient struct {
 // NewTradingClient returns a new chan client if the connection with the
 // cryptomarket server is successful and if the authentication is successfull.
 // return error otherwise.
-func NewTradingClient(apiKey, apiSecret string) (*TradingClient, error) {
+func NewTradingClient(apiKey, apiSecret string, options ...Option) (*TradingClient, error) {
+	return NewTradingClientWithSigner(auth.HS256(apiKey, apiSecret), options...)
+}
+
+// NewTradingClientWithSigner returns a new TradingClient logged in with the params
+// of signer, e.g. auth.Basic or an auth.External signer with the secret
+// kept out of the process.
+func NewTradingClientWithSigner(signer auth.Signer, options ...Option) (*TradingClient, error) {
+	return NewTradingClientContext(context.Background(), signer, options...)
+}
+
+// NewTradingClientContext returns a new TradingClient logged in with the params of
+// signer, with a context bounding the connection and the login.
+func NewTradingClientContext(ctx context.Context, signer auth.Signer, options ...Option) (*TradingClient, error) {
+	client := newTradingClient(newWSManager("/api/2/ws/trading"))
+	client.apply(options)
+
+	// connect to streaming
+	if err := client.wsManager.connect(ctx); err != nil {
+		return nil, fmt.Errorf("Error in websocket client connection: %s", err)
+	}
+	// handle incomming data
+	go client.handle(client.wsManager.rcv)
+
+	if err := client.authenticate(ctx, signer); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// SetCredentials logs in again with a new api key and secret, signing with
+// HS256, on the same connection. Subscriptions and pending requests are kept.
+// Returns the error of the exchange if the new credentials are rejected.
+func (client *TradingClient) SetCredentials(apiKey, apiSecret string) error {
+	return client.SetSigner(auth.HS256(apiKey, apiSecret))
+}
+
+// SetSigner logs in again with a new signer on the same connection.
+// Subscriptions and pending requests are kept.
+func (client *TradingClient) SetSigner(signer auth.Signer) error {
+	return client.SetSignerContext(context.Background(), signer)
+}
+
+// SetSignerContext is SetSigner with a context bounding the login, instead
+// of the timeout of the client.
+func (client *TradingClient) SetSignerContext(ctx context.Context, signer auth.Signer) error {
+	return client.relogin(ctx, signer)
+}
+
+// newTradingClient builds a TradingClient over a manager, without connecting.
+func newTradingClient(manager *wsManager) *TradingClient {
 	methodMapping := map[string]string{
 		// reports
 		"subscribeReports":   "reports",
@@ -25,10 +78,12 @@ func NewTradingClient(apiKey, apiSecret string) (*TradingClient, error) {
 		"activeOrders":       "reports",
 		"report":             "reports",
 	}
-	client := &TradingClient{
+	return &TradingClient{
 		clientBase: clientBase{
-			wsManager: newWSManager("/api/2/ws/trading"),
-			chanCache: newChanCache(),
+			wsManager:    manager,
+			chanCache:    newChanCache(),
+			capabilities: auth.AllCapabilities,
+			timeout:      defaultTimeout,
 			subscriptionKeysFunc: func(method string, params map[string]interface{}) (string, bool) {
 				val, ok := methodMapping[method]
 				return val, ok
@@ -38,18 +93,16 @@ func NewTradingClient(apiKey, apiSecret string) (*TradingClient, error) {
 			},
 		},
 	}
+}
 
-	// connect to streaming
-	if err := client.wsManager.connect(); err != nil {
-		return nil, fmt.Errorf("Error in websocket client connection: %s", err)
-	}
-	// handle incomming data
-	go client.handle(client.wsManager.rcv)
-
-	if err := client.authenticate(apiKey, apiSecret); err != nil {
+// NewTradingClientFromProvider returns a new TradingClient authenticated with the
+// credentials of a provider, such as credentials.Default("").
+func NewTradingClientFromProvider(ctx context.Context, provider credentials.Provider, options ...Option) (*TradingClient, error) {
+	keys, err := provider.Retrieve(ctx)
+	if err != nil {
 		return nil, err
 	}
-	return client, nil
+	return NewTradingClientContext(ctx, auth.HS256(keys.APIKey, keys.APISecret), options...)
 }
 
 // GetTradingBalance Get the user trading balance.
@@ -160,30 +213,74 @@ func (client *TradingClient) ReplaceOrder(ctx context.Context, arguments ...args
 //
 // https://api.exchange.cryptomarket.com/#subscribe-to-reports
 func (client *TradingClient) SubscribeToReports() (feedCh chan models.Report, err error) {
-	dataCh, err := client.doSubscription(methodSubscribeReports, nil, nil)
+	return client.SubscribeToReportsContext(context.Background())
+}
+
+// SubscribeToReportsContext is SubscribeToReports with a context bounding the subscription request,
+// instead of the timeout of the client.
+func (client *TradingClient) SubscribeToReportsContext(ctx context.Context) (feedCh chan models.Report, err error) {
+	feedCh, _, err = client.subscribeToReports(ctx, nil)
+	return feedCh, err
+}
+
+// ReportsSubscription is a subscription to the reports of the account, with its feed in C.
+type ReportsSubscription struct {
+	*Subscription
+	C <-chan models.Report
+}
+
+// ReportsSubscription is SubscribeToReportsContext returning the handle of the
+// subscription, with the arguments of the feed.
+//
+// Arguments:
+//  Buffer(int)            // Optional. Size of the buffer of the feed channel
+//  Overflow(OverflowType) // Optional. Policy of the feed channel when full. Default is OverflowTypeBlock
+func (client *TradingClient) ReportsSubscription(ctx context.Context, arguments ...args.Argument) (*ReportsSubscription, error) {
+	feedCh, sub, err := client.subscribeToReports(ctx, arguments)
 	if err != nil {
 		return nil, err
 	}
-	feedCh = make(chan models.Report)
-	go func() {
-		defer close(feedCh)
-		// the first time it recieves a list of reports
-		var reports struct {
-			Params []models.Report
-		}
-		data := <-dataCh
-		json.Unmarshal(data, &reports)
-		for _, report := range reports.Params {
-			feedCh <- report
+	return &ReportsSubscription{Subscription: sub, C: feedCh}, nil
+}
+
+// subscribeToReports subscribes to the reports. The exchange has no
+// unsubscription of reports, the handle of the subscription only drops it
+// locally.
+func (client *TradingClient) subscribeToReports(ctx context.Context, arguments []args.Argument) (chan models.Report, *Subscription, error) {
+	sub, c, err := client.newSubscription(ctx, methodSubscribeReports, "", arguments, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	feedCh := make(chan models.Report, sub.buffer)
+	go sub.run(c, feedCh, func(data []byte) error {
+		var method struct {
+			Method string
 		}
-		// then recieves one report at a time
-		var resp struct {
-			Params models.Report
+		json.Unmarshal(data, &method)
+		var reports []models.Report
+		if method.Method == methodActiveOrders {
+			// the subscription starts with a list of the active orders
+			var resp struct {
+				Params []models.Report
+			}
+			if err := json.Unmarshal(data, &resp); err != nil {
+				return err
+			}
+			reports = resp.Params
+		} else {
+			// then recieves one report at a time
+			var resp struct {
+				Params models.Report
+			}
+			if err := json.Unmarshal(data, &resp); err != nil {
+				return err
+			}
+			reports = []models.Report{resp.Params}
 		}
-		for data := range dataCh {
-			json.Unmarshal(data, &resp)
-			feedCh <- resp.Params
+		for _, report := range reports {
+			sub.send(report)
 		}
-	}()
-	return feedCh, nil
+		return nil
+	})
+	return feedCh, sub, nil
 }
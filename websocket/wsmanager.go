@@ -1,11 +1,13 @@
 package websocket
 
 import (
-	"flag"
+	"context"
 	"fmt"
-	"log"
 	"net/url"
 
+	"github.com/cryptomarket/cryptomarket-go/logging"
+	"github.com/cryptomarket/cryptomarket-go/metrics"
+	"github.com/cryptomarket/cryptomarket-go/tracing"
 	"github.com/gorilla/websocket"
 )
 
@@ -21,6 +23,9 @@ type wsManager struct {
 	snd        chan []byte
 	rcv        chan []byte
 	isOpen     bool
+	logger     logging.Logger
+	metrics    metrics.Recorder
+	tracer     tracing.Tracer
 }
 
 func newWSManager(path string) *wsManager {
@@ -29,21 +34,23 @@ func newWSManager(path string) *wsManager {
 		snd:        make(chan []byte, 1),
 		rcv:        make(chan []byte, 1),
 		isOpen:     false,
+		logger:     logging.Discard,
+		metrics:    metrics.Discard,
+		tracer:     tracing.Noop,
 	}
 }
 
-func (ws *wsManager) connect() error {
-	flag.Parse()
-	log.SetFlags(0)
-
+func (ws *wsManager) connect(ctx context.Context) error {
 	u := url.URL{Scheme: "wss", Host: addr, Path: ws.streamPath}
-	log.Printf("connecting to %s", u.String())
+	ws.logger.Info("connecting", "url", u.String())
 
-	c, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+	c, _, err := websocket.DefaultDialer.DialContext(ctx, u.String(), nil)
 	if err != nil {
+		ws.logger.Error("connection failed", "url", u.String(), "error", err)
 		return fmt.Errorf("dial: %v", err)
 	}
 	ws.conn = c
+	ws.logger.Info("connected", "url", u.String())
 
 	go ws.rcvLoop()
 	go ws.sndLoop()
@@ -61,14 +68,14 @@ func (ws *wsManager) sndLoop() {
 	for msg := range ws.snd {
 		err := ws.conn.WriteMessage(websocket.TextMessage, msg)
 		if err != nil {
-			fmt.Println("write:", err)
+			ws.logger.Error("write failed", "path", ws.streamPath, "error", err)
 			return
 		}
 	}
 	// send close msg to server
 	err := ws.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
 	if err != nil {
-		fmt.Println("write close:", err)
+		ws.logger.Error("close failed", "path", ws.streamPath, "error", err)
 		return
 	}
 }
@@ -79,7 +86,10 @@ func (ws *wsManager) rcvLoop() {
 	for {
 		_, message, err := ws.conn.ReadMessage()
 		if err != nil {
-			fmt.Println("read:", err)
+			ws.logger.Warn("connection closed", "path", ws.streamPath, "error", err)
+			if ws.isOpen {
+				ws.metrics.Add(metrics.WSDisconnects, metrics.Labels{"path": ws.streamPath}, 1)
+			}
 			ws.conn.Close()
 			return
 		}